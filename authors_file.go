@@ -0,0 +1,411 @@
+package duet
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ProposeInitials suggests initials for name that aren't already taken,
+// starting with the first letters of the first and last name (e.g. jd for Jane
+// Doe) and using more letters or a number on collision
+func ProposeInitials(name string, taken map[string]bool) (initials string) {
+	var words []string
+	for _, word := range strings.Fields(strings.ToLower(name)) {
+		word = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) {
+				return r
+			}
+			return -1
+		}, word)
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		words = []string{"x"}
+	}
+
+	first, last := []rune(words[0]), []rune(words[len(words)-1])
+	var candidates []string
+	if len(words) == 1 {
+		for i := 2; i <= len(first); i++ {
+			candidates = append(candidates, string(first[:i]))
+		}
+	} else {
+		candidates = append(candidates, string(first[0])+string(last[0]))
+		for i := 2; i <= len(last); i++ {
+			candidates = append(candidates, string(first[0])+string(last[:i]))
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = []string{string(first)}
+	}
+
+	for _, candidate := range candidates {
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s%d", candidates[0], n)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// WriteAuthorsFile writes a (version 1) authors file listing the given pairs
+// Every email is listed under `email_addresses` so that they don't depend on
+// how email addresses are built, the most common domain becomes the default
+// domain for anyone added later
+func WriteAuthorsFile(w io.Writer, pairs []*Pair) (err error) {
+	af := &pairsFile{
+		Pairs:          map[string]string{},
+		EmailAddresses: map[string]string{},
+	}
+
+	domains := map[string]int{}
+	for _, p := range pairs {
+		af.Pairs[p.Initials] = p.Name
+		if p.Username != "" {
+			af.Pairs[p.Initials] = p.Name + "; " + p.Username
+		}
+
+		if p.Email != "" {
+			af.EmailAddresses[p.Initials] = p.Email
+			if at := strings.LastIndex(p.Email, "@"); at >= 0 {
+				domain := p.Email[at+1:]
+				domains[domain]++
+				if domains[domain] > domains[af.Email.Domain] ||
+					domains[domain] == domains[af.Email.Domain] && domain < af.Email.Domain {
+					af.Email.Domain = domain
+				}
+			}
+		}
+	}
+
+	contents, err := yaml.Marshal(af)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append([]byte("---\n"), contents...))
+	return err
+}
+
+// AuthorsFile is an authors file loaded for editing line by line, so that
+// comments, formatting and the order of keys survive (unlike a YAML round
+// trip)
+type AuthorsFile struct {
+	filename string
+	lines    []string
+}
+
+// section is the range of lines belonging to a top-level key
+// start is the line of the key, end is the line after its last entry
+type section struct {
+	start, end int
+}
+
+var (
+	topLevelKey = regexp.MustCompile(`^([A-Za-z_]+):\s*(#.*)?$`)
+	entryLine   = regexp.MustCompile(`^(\s+)(?:"([^"]*)"|'([^']*)'|([^\s:#'"][^:]*?))\s*:(?:\s+(.*))?$`)
+)
+
+// LoadAuthorsFile reads the authors file for editing
+func LoadAuthorsFile(filename string) (f *AuthorsFile, err error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, withKind(ErrAuthorsFileInvalid, err)
+	}
+
+	return &AuthorsFile{
+		filename: filename,
+		lines:    strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n"),
+	}, nil
+}
+
+func (f *AuthorsFile) contents() []byte {
+	return []byte(strings.Join(f.lines, "\n") + "\n")
+}
+
+// Pairs parses the edited authors file
+func (f *AuthorsFile) Pairs(lookup EmailLookup) (a *Pairs, err error) {
+	return newPairs(f.filename, f.contents(), lookup)
+}
+
+// Save writes the edited authors file back
+func (f *AuthorsFile) Save() (err error) {
+	return ioutil.WriteFile(f.filename, f.contents(), 0644)
+}
+
+// section finds the block of the first of the given top-level keys
+func (f *AuthorsFile) section(keys ...string) (s *section, err error) {
+	for i, line := range f.lines {
+		match := topLevelKey.FindStringSubmatch(line)
+		if match == nil {
+			if strings.HasPrefix(line, "authors:") || strings.HasPrefix(line, "pairs:") {
+				return nil, fmt.Errorf("cannot edit %s: %s must be a block mapping", f.filename, strings.SplitN(line, ":", 2)[0])
+			}
+			continue
+		}
+
+		for _, key := range keys {
+			if match[1] != key {
+				continue
+			}
+
+			s = &section{start: i, end: i + 1}
+			for j := i + 1; j < len(f.lines); j++ {
+				trimmed := strings.TrimSpace(f.lines[j])
+				if trimmed != "" && !strings.HasPrefix(f.lines[j], " ") && !strings.HasPrefix(f.lines[j], "\t") {
+					if strings.HasPrefix(trimmed, "#") {
+						continue
+					}
+					break
+				}
+				if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+					s.end = j + 1
+				}
+			}
+			return s, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// entry returns the index of the line with the given key in the section (-1
+// if missing)
+func (f *AuthorsFile) entry(s *section, key string) int {
+	if s == nil {
+		return -1
+	}
+
+	for i := s.start + 1; i < s.end; i++ {
+		if match := entryLine.FindStringSubmatch(f.lines[i]); match != nil && match[2]+match[3]+match[4] == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// indent returns the indentation used by the entries of the section
+func (f *AuthorsFile) indent(s *section) string {
+	for i := s.start + 1; i < s.end; i++ {
+		if match := entryLine.FindStringSubmatch(f.lines[i]); match != nil {
+			return match[1]
+		}
+	}
+	return "  "
+}
+
+// set replaces the entry with the given key or adds it to the end of the
+// section, creating the section at the end of the file if needed
+func (f *AuthorsFile) set(key, value string, sectionKeys ...string) (err error) {
+	s, err := f.section(sectionKeys...)
+	if err != nil {
+		return err
+	}
+
+	if s == nil {
+		f.lines = append(f.lines, sectionKeys[0]+":")
+		s = &section{start: len(f.lines) - 1, end: len(f.lines)}
+	}
+
+	line := f.indent(s) + yamlScalar(key) + ": " + yamlScalar(value)
+	if i := f.entry(s, key); i >= 0 {
+		f.lines[i] = line
+		return nil
+	}
+
+	f.lines = append(f.lines[:s.end], append([]string{line}, f.lines[s.end:]...)...)
+	return nil
+}
+
+// remove deletes the entry with the given key from the section if present
+func (f *AuthorsFile) remove(key string, sectionKeys ...string) (err error) {
+	s, err := f.section(sectionKeys...)
+	if err != nil {
+		return err
+	}
+
+	if i := f.entry(s, key); i >= 0 {
+		f.lines = append(f.lines[:i], f.lines[i+1:]...)
+	}
+	return nil
+}
+
+// rename changes the key of an entry in the section if present
+func (f *AuthorsFile) rename(from, to string, sectionKeys ...string) (err error) {
+	s, err := f.section(sectionKeys...)
+	if err != nil {
+		return err
+	}
+
+	if i := f.entry(s, from); i >= 0 {
+		match := entryLine.FindStringSubmatch(f.lines[i])
+		f.lines[i] = match[1] + yamlScalar(to) + ":"
+		if match[5] != "" {
+			f.lines[i] += " " + match[5]
+		}
+	}
+	return nil
+}
+
+// renameMember replaces initials in the member lists of teams (either
+// `[a, b]` or one `- a` per line); an empty replacement removes them
+func (f *AuthorsFile) renameMember(from, to string) (err error) {
+	s, err := f.section("teams")
+	if err != nil || s == nil {
+		return err
+	}
+
+	member := regexp.MustCompile(`(^|[\[,]\s*|^\s*-\s+)` + regexp.QuoteMeta(from) + `(\s*(?:[\],#]|$))`)
+	for i := s.end - 1; i > s.start; i-- {
+		if !member.MatchString(f.lines[i]) {
+			continue
+		}
+
+		if to != "" {
+			f.lines[i] = member.ReplaceAllString(f.lines[i], "${1}"+to+"${2}")
+		} else if strings.HasPrefix(strings.TrimSpace(f.lines[i]), "-") {
+			f.lines = append(f.lines[:i], f.lines[i+1:]...)
+		} else {
+			line := member.ReplaceAllString(f.lines[i], "${1}${2}")
+			line = regexp.MustCompile(`\[\s*,\s*`).ReplaceAllString(line, "[")
+			line = regexp.MustCompile(`,\s*,`).ReplaceAllString(line, ",")
+			f.lines[i] = regexp.MustCompile(`,\s*\]`).ReplaceAllString(line, "]")
+		}
+	}
+	return nil
+}
+
+// Add adds an author with the given initials
+// The email and username are optional, the email is listed under
+// `email_addresses` if given
+// Returns an error if the initials are taken or no valid email address can
+// be built for the author
+func (f *AuthorsFile) Add(initials, name, username, email string, lookup EmailLookup) (err error) {
+	pairs, err := f.Pairs(lookup)
+	if err != nil {
+		return err
+	}
+	if _, ok := pairs.file.Pairs[initials]; ok {
+		return fmt.Errorf("initials %s are already taken by %s", initials, pairs.file.Pairs[initials])
+	}
+	if !ValidInitials(initials) {
+		return fmt.Errorf("invalid initials %s", initials)
+	}
+
+	value := name
+	if username != "" {
+		value = name + "; " + username
+	}
+	if err = f.set(initials, value, "authors", "pairs"); err != nil {
+		return err
+	}
+
+	if email != "" {
+		if err = f.set(initials, email, "email_addresses"); err != nil {
+			return err
+		}
+	}
+
+	return f.validate(initials, lookup)
+}
+
+// Remove removes the author with the given initials, including their email
+// address and team memberships
+func (f *AuthorsFile) Remove(initials string) (err error) {
+	s, err := f.section("authors", "pairs")
+	if err != nil {
+		return err
+	}
+	if f.entry(s, initials) < 0 {
+		return &UnknownInitialsError{Initials: initials}
+	}
+
+	if err = f.remove(initials, "authors", "pairs"); err != nil {
+		return err
+	}
+	if err = f.remove(initials, "email_addresses"); err != nil {
+		return err
+	}
+	return f.renameMember(initials, "")
+}
+
+// Rename changes the initials of an author, including in their email address
+// and team memberships
+func (f *AuthorsFile) Rename(from, to string, lookup EmailLookup) (err error) {
+	s, err := f.section("authors", "pairs")
+	if err != nil {
+		return err
+	}
+	if f.entry(s, from) < 0 {
+		return &UnknownInitialsError{Initials: from}
+	}
+	if f.entry(s, to) >= 0 {
+		return fmt.Errorf("initials %s are already taken", to)
+	}
+	if !ValidInitials(to) {
+		return fmt.Errorf("invalid initials %s", to)
+	}
+
+	if err = f.rename(from, to, "authors", "pairs"); err != nil {
+		return err
+	}
+	if err = f.rename(from, to, "email_addresses"); err != nil {
+		return err
+	}
+	if err = f.renameMember(from, to); err != nil {
+		return err
+	}
+
+	return f.validate(to, lookup)
+}
+
+// validate checks that the edited file parses and builds a valid email
+// address for the author with the given initials
+func (f *AuthorsFile) validate(initials string, lookup EmailLookup) (err error) {
+	pairs, err := f.Pairs(lookup)
+	if err != nil {
+		return err
+	}
+
+	pair, err := pairs.ByInitials(initials)
+	if err != nil {
+		return err
+	}
+
+	if _, err = mail.ParseAddress(pair.Email); err != nil {
+		return fmt.Errorf("invalid email address %q for %s", pair.Email, initials)
+	}
+	return nil
+}
+
+var validInitials = regexp.MustCompile(`^[^\s:#;,\[\]'"]+$`)
+
+// ValidInitials returns whether initials can be used as a key in the authors
+// file and on the command line
+func ValidInitials(initials string) bool {
+	return validInitials.MatchString(initials)
+}
+
+// yamlScalar quotes the value if it would not be read back as the same
+// plain string
+func yamlScalar(value string) string {
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return value
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
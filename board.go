@@ -0,0 +1,117 @@
+package duet
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// BoardPath is where a team keeps its pairing board, relative to the top level
+// of the repository
+const BoardPath = ".git-duet/board.yaml"
+
+// Sections of the pairing board: pairings by machine (hostname) and by branch
+const (
+	BoardHosts    = "hosts"
+	BoardBranches = "branches"
+)
+
+// Board is the pairing board of a team: the initials (or teams) pairing at
+// each machine and on each branch, space separated
+type Board struct {
+	Hosts    map[string]string `yaml:"hosts"`
+	Branches map[string]string `yaml:"branches"`
+}
+
+// ReadBoard parses the board file
+func ReadBoard(filename string) (board *Board, err error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parseBoard(filename, contents)
+}
+
+func parseBoard(filename string, contents []byte) (board *Board, err error) {
+	board = &Board{}
+	if err = yaml.Unmarshal(contents, board); err != nil {
+		return nil, withKind(ErrConfigInvalid, fmt.Errorf("invalid board %s: %v", filename, err))
+	}
+	return board, nil
+}
+
+// Lookup returns the initials on the board for the branch or, failing that,
+// for the host (by its full or short name, in any case), and the entry they
+// were found under (e.g. "branch main"); nil if neither is on the board
+func (b *Board) Lookup(host, branch string) (names []string, entry string) {
+	if pairing, ok := b.Branches[branch]; ok && branch != "" {
+		return strings.Fields(pairing), "branch " + branch
+	}
+
+	if pairing, ok := b.Hosts[host]; ok && host != "" {
+		return strings.Fields(pairing), "host " + host
+	}
+	short := strings.SplitN(host, ".", 2)[0]
+	for name, pairing := range b.Hosts {
+		if strings.EqualFold(name, host) || strings.EqualFold(name, short) {
+			return strings.Fields(pairing), "host " + name
+		}
+	}
+	return nil, ""
+}
+
+// BoardFile is a board file loaded for editing line by line, like
+// AuthorsFile, so that comments and the order of entries survive
+type BoardFile struct {
+	file *AuthorsFile
+}
+
+// LoadBoardFile reads the board file for editing, a missing one is empty
+func LoadBoardFile(filename string) (f *BoardFile, err error) {
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return &BoardFile{file: &AuthorsFile{filename: filename, lines: []string{"---"}}}, nil
+	}
+
+	file, err := LoadAuthorsFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &BoardFile{file: file}, nil
+}
+
+// Set puts the initials on the board for the host or branch (see BoardHosts
+// and BoardBranches), removing the entry if there are none
+func (f *BoardFile) Set(section, name string, names ...string) (err error) {
+	if section != BoardHosts && section != BoardBranches {
+		return fmt.Errorf("unknown board section %s", section)
+	}
+
+	if len(names) == 0 {
+		err = f.file.remove(name, section)
+	} else {
+		err = f.file.set(name, strings.Join(names, " "), section)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Board()
+	return err
+}
+
+// Board parses the edited board file
+func (f *BoardFile) Board() (board *Board, err error) {
+	return parseBoard(f.file.filename, f.file.contents())
+}
+
+// Save writes the edited board file back, creating its directory if needed
+func (f *BoardFile) Save() (err error) {
+	if err = os.MkdirAll(filepath.Dir(f.file.filename), os.ModePerm); err != nil {
+		return err
+	}
+	return f.file.Save()
+}
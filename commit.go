@@ -0,0 +1,120 @@
+package duet
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+)
+
+// Keys of the trailers crediting pairs in commit messages
+const (
+	CoAuthoredBy = "Co-authored-by"
+	SignedOffBy  = "Signed-off-by"
+)
+
+// Trailer returns a commit message trailer crediting the pair, e.g.
+// "Co-authored-by: Jane Doe <jane@hamster.info.local>"
+func (p *Pair) Trailer(key string) string {
+	return fmt.Sprintf("%s: %s <%s>", key, p.Name, p.Email)
+}
+
+// Trailers returns a trailer with the given key for each pair
+func Trailers(key string, pairs []*Pair) (trailers []string) {
+	for _, p := range pairs {
+		trailers = append(trailers, p.Trailer(key))
+	}
+	return trailers
+}
+
+// CommitIdentity is what git needs to commit as a pairing: the author and
+// committer as environment variables, arguments for git signing off by the
+// committers, and options for git itself (before the command) setting the
+// signing key of the committer
+type CommitIdentity struct {
+	Author    *Pair
+	Committer *Pair
+	Env       []string
+	Args      []string
+	GitArgs   []string
+}
+
+// CommitIdentity returns how to commit as the author with the committers
+// If signoff is set, the first committer commits and signs off (all of them
+// sign off with AllowMultipleCommitters), otherwise the author commits
+func (c *Configuration) CommitIdentity(author *Pair, committers []*Pair, signoff bool) *CommitIdentity {
+	identity := &CommitIdentity{Author: author, Committer: author}
+
+	if len(committers) > 0 && signoff {
+		identity.Committer = committers[0]
+		if len(committers) > 1 && c.AllowMultipleCommitters {
+			for _, trailer := range Trailers(SignedOffBy, committers) {
+				identity.Args = append(identity.Args, "--trailer", trailer)
+			}
+		} else {
+			identity.Args = []string{"--signoff"}
+		}
+	}
+
+	identity.Env = []string{
+		fmt.Sprintf("GIT_AUTHOR_NAME=%s", identity.Author.Name),
+		fmt.Sprintf("GIT_AUTHOR_EMAIL=%s", identity.Author.Email),
+		fmt.Sprintf("GIT_COMMITTER_NAME=%s", identity.Committer.Name),
+		fmt.Sprintf("GIT_COMMITTER_EMAIL=%s", identity.Committer.Email),
+	}
+
+	// git signs as the committer, when it's asked to sign
+	if identity.Committer.SigningKey != "" {
+		identity.GitArgs = []string{"-c", "user.signingkey=" + identity.Committer.SigningKey}
+	}
+
+	return identity
+}
+
+var coAuthorTrailerRegexp = regexp.MustCompile(`Co-authored-by:\s.+\s<.+>`)
+
+// AddCoAuthoredBy adds a Co-authored-by trailer for each committer to a commit
+// message file, as the prepare-commit-msg hook does, using git
+// interpret-trailers
+// source is the source of the message git passes to the hook ("commit" when
+// amending)
+func AddCoAuthoredBy(ctx context.Context, runner Runner, messageFile, source string, committers []*Pair) error {
+	if len(committers) == 0 {
+		return nil
+	}
+
+	message, err := ioutil.ReadFile(messageFile)
+	if err != nil {
+		return err
+	}
+
+	trailerExists := coAuthorTrailerRegexp.Match(message)
+	if trailerExists && source != "commit" {
+		/* The goal here is to not add trailers in interactive rebasing or cherry-picking
+		   since authorship doesn't get changed. Since this hook doesn't know whether it is invoked
+		   as part of rebasing or cherry-picking, at the very least, it checks for existing trailers,
+		   and if there is one, no new trailers will be appended.
+		   Trailers will still be appended for "git commit --amend" in which case the
+		   source's value is "commit". */
+		return nil
+	}
+
+	for _, trailer := range Trailers(CoAuthoredBy, committers) {
+		cmd := &Command{
+			Name: "git",
+			Args: []string{"interpret-trailers", "--in-place", "--trailer", trailer, messageFile},
+		}
+		if err = run(ctx, runner, cmd); err != nil {
+			return err
+		}
+	}
+
+	// prepend an empty line to the trailers block if there aren't trailers yet
+	if trailerExists || source == "commit" {
+		return nil
+	}
+	if message, err = ioutil.ReadFile(messageFile); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(messageFile, []byte(fmt.Sprintf("\n%s", string(message))), 0644)
+}
@@ -0,0 +1,105 @@
+package duet_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	duet "github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/duettest"
+)
+
+var frances = &duet.Pair{Initials: "fb", Name: "Frances Bar", Email: "frances@hamster.info.local"}
+
+func TestAddCoAuthoredBy(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		message string
+		source  string
+		want    string
+	}{
+		{
+			name:    "starts a trailers block",
+			message: "Add the thing\n",
+			source:  "message",
+			want:    "\nAdd the thing\n\nCo-authored-by: Jane Doe <jane@hamster.info.local>\nCo-authored-by: Frances Bar <frances@hamster.info.local>\n",
+		},
+		{
+			name:    "leaves messages with trailers alone",
+			message: "Add the thing\n\nCo-authored-by: Zubaz Shirts <z.shirts@pika.info.local>\n",
+			source:  "message",
+			want:    "Add the thing\n\nCo-authored-by: Zubaz Shirts <z.shirts@pika.info.local>\n",
+		},
+		{
+			name:    "adds to the trailers of an amended commit",
+			message: "Add the thing\n\nCo-authored-by: Zubaz Shirts <z.shirts@pika.info.local>\n",
+			source:  "commit",
+			want:    "Add the thing\n\nCo-authored-by: Zubaz Shirts <z.shirts@pika.info.local>\nCo-authored-by: Jane Doe <jane@hamster.info.local>\nCo-authored-by: Frances Bar <frances@hamster.info.local>\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "git-duet")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			messageFile := filepath.Join(dir, "COMMIT_EDITMSG")
+			if err = ioutil.WriteFile(messageFile, []byte(test.message), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			git := duettest.NewGit(dir)
+			err = duet.AddCoAuthoredBy(context.Background(), git, messageFile, test.source, []*duet.Pair{jane, frances})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			message, err := ioutil.ReadFile(messageFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(message) != test.want {
+				t.Errorf("got message %q, want %q", message, test.want)
+			}
+		})
+	}
+}
+
+func TestAddCoAuthoredByWithoutCommitters(t *testing.T) {
+	git := duettest.NewGit("/src/project")
+
+	if err := duet.AddCoAuthoredBy(context.Background(), git, "/missing/COMMIT_EDITMSG", "message", nil); err != nil {
+		t.Fatal(err)
+	}
+	if commands := git.Commands(); len(commands) != 0 {
+		t.Errorf("ran %v without committers", commands)
+	}
+}
+
+func TestCommitIdentitySigningKey(t *testing.T) {
+	config, err := duet.ParseConfiguration(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signing := &duet.Pair{Initials: "fb", Name: "Frances Bar", Email: "frances@hamster.info.local", SigningKey: "0xFRANCES"}
+
+	for _, test := range []struct {
+		name    string
+		signoff bool
+		want    []string
+	}{
+		{name: "signs as the committer", signoff: true, want: []string{"-c", "user.signingkey=0xFRANCES"}},
+		{name: "signs as the author without signing off"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			identity := config.CommitIdentity(jane, []*duet.Pair{signing}, test.signoff)
+			if strings.Join(identity.GitArgs, " ") != strings.Join(test.want, " ") {
+				t.Errorf("got git args %q, want %q", identity.GitArgs, test.want)
+			}
+		})
+	}
+}
@@ -2,101 +2,408 @@ package duet
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 )
 
 // Configuration represents package configuration (shared by commands)
+// Dir is the repository it was loaded for (the current directory if empty)
+// and IsCurrentWorkingDirGitRepo whether Dir is in a repository (including
+// bare ones and GIT_DIR)
+// CacheDir keeps the email lookup cache (see DefaultCacheDir), which
+// RefreshEmailLookup bypasses
 type Configuration struct {
+	Dir                        string
 	Namespace                  string
 	PairsFile                  string
 	EmailLookup                string
+	EmailLookupProtocol        int
+	EmailLookupTimeout         time.Duration
+	EmailLookupFallback        bool
+	EmailLookupCacheTTL        time.Duration
+	EmailLookupNegativeTTL     time.Duration
 	CoAuthoredBy               bool
 	Global                     bool
 	RotateAuthor               bool
+	RotationStrategy           string
+	RotationCommits            int
+	RotationInterval           time.Duration
+	DriverSource               string
 	SetGitUserConfig           bool
 	StaleCutoff                time.Duration
+	StalePolicy                string
+	StaleSince                 string
+	ExpireAt                   string
 	IsCurrentWorkingDirGitRepo bool
 	DefaultUpdate              bool
 	AllowMultipleCommitters    bool
+	Notes                      bool
+	CacheDir                   string
+	RefreshEmailLookup         bool
 }
 
-// NewConfiguration initializes Configuration from the environment
-// Returns an error if it cannot parse the staleness timeout as an integer or
-// the global var as a bool
+// Staleness policies applied by git-duet-pre-commit once the pairing is older
+// than the configured cutoff
+// StaleBlock rejects the commit
+// StaleWarn prints a warning and lets the commit through
+// StalePrompt asks whether the pairing is still current (blocks without a TTY)
+// StaleSolo reverts to the last soloist and rejects the commit
+const (
+	StaleBlock  = "block"
+	StaleWarn   = "warn"
+	StalePrompt = "prompt"
+	StaleSolo   = "solo"
+)
+
+// Setting is an option read by NewConfiguration from, in order of
+// precedence, an environment variable, a git config key (repository, then
+// global) or its default
+type Setting struct {
+	Env     string
+	Key     string
+	Default string
+	Value   string
+	Source  string
+}
+
+// Sources of a setting besides the git config scopes reported by git
+// (local, global, system, ...)
+const (
+	SourceEnv     = "env"
+	SourceDefault = "default"
+)
+
+// settings lists every option, the ones with an empty default get theirs from
+// other settings or the environment (see Settings)
+var settings = []Setting{
+	{Env: "GIT_DUET_CONFIG_NAMESPACE", Key: "duet.configNamespace", Default: "duet.env"},
+	{Env: "GIT_DUET_AUTHORS_FILE", Key: "duet.authorsFile"},
+	{Env: "GIT_DUET_EMAIL_LOOKUP_COMMAND", Key: "duet.emailLookupCommand"},
+	{Env: "GIT_DUET_EMAIL_LOOKUP_PROTOCOL", Key: "duet.emailLookupProtocol", Default: "1"},
+	{Env: "GIT_DUET_EMAIL_LOOKUP_TIMEOUT", Key: "duet.emailLookupTimeout", Default: "0"},
+	{Env: "GIT_DUET_EMAIL_LOOKUP_FALLBACK", Key: "duet.emailLookupFallback", Default: "0"},
+	{Env: "GIT_DUET_EMAIL_LOOKUP_CACHE_TTL", Key: "duet.emailLookupCacheTTL", Default: "0"},
+	{Env: "GIT_DUET_EMAIL_LOOKUP_NEGATIVE_TTL", Key: "duet.emailLookupNegativeTTL", Default: "0"},
+	{Env: "GIT_DUET_GLOBAL", Key: "duet.global", Default: "0"},
+	{Env: "GIT_DUET_CO_AUTHORED_BY", Key: "duet.coAuthoredBy", Default: "0"},
+	{Env: "GIT_DUET_SET_GIT_USER_CONFIG", Key: "duet.setGitUserConfig"},
+	{Env: "GIT_DUET_ROTATE_AUTHOR", Key: "duet.rotateAuthor", Default: "0"},
+	{Env: "GIT_DUET_ROTATION_STRATEGY", Key: "duet.rotation.strategy", Default: RotateRoundRobin},
+	{Env: "GIT_DUET_ROTATION_COMMITS", Key: "duet.rotation.commits", Default: "1"},
+	{Env: "GIT_DUET_ROTATION_INTERVAL", Key: "duet.rotation.interval", Default: "15m"},
+	{Env: "GIT_DUET_DRIVER_SOURCE", Key: "duet.driverSource"},
+	{Env: "GIT_DUET_SECONDS_AGO_STALE", Key: "duet.secondsAgoStale", Default: "1200"},
+	{Env: "GIT_DUET_STALE_POLICY", Key: "duet.stalePolicy", Default: StaleBlock},
+	{Env: "GIT_DUET_STALE_SINCE", Key: "duet.staleSince", Default: StaleSinceSet},
+	{Env: "GIT_DUET_EXPIRE_AT", Key: "duet.expireAt"},
+	{Env: "GIT_DUET_DEFAULT_UPDATE", Key: "duet.defaultUpdate", Default: "0"},
+	{Env: "GIT_DUET_ALLOW_MULTIPLE_COMMITTERS", Key: "duet.allowMultipleCommitters", Default: "0"},
+	{Env: "GIT_DUET_NOTES", Key: "duet.notes", Default: "0"},
+}
+
+// Loader reads the configuration from the environment and git config
+// Dir is the repository to read it for (the current directory if empty),
+// Runner runs git (ExecRunner if nil), Getenv looks up environment variables
+// (os.Getenv if nil) and Stderr gets the errors of git (os.Stderr if nil)
+type Loader struct {
+	Dir    string
+	Runner Runner
+	Getenv func(key string) string
+	Stderr io.Writer
+}
+
+func (l *Loader) getenv(key string) string {
+	if l.Getenv == nil {
+		return os.Getenv(key)
+	}
+	return l.Getenv(key)
+}
+
+func (l *Loader) stderr() io.Writer {
+	if l.Stderr == nil {
+		return os.Stderr
+	}
+	return l.Stderr
+}
+
+// Settings returns every option with its effective value and where that
+// value came from
+func Settings() (resolved []Setting, err error) {
+	return (&Loader{}).Settings(context.Background())
+}
+
+// Settings is Settings with the Loader's runner and environment
+func (l *Loader) Settings(ctx context.Context) (resolved []Setting, err error) {
+	gitSettings, err := l.readGitSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for _, setting := range settings {
+		if value := l.getenv(setting.Env); value != "" {
+			setting.Value, setting.Source = value, SourceEnv
+		} else if v, ok := gitSettings[strings.ToLower(setting.Key)]; ok && v.value != "" {
+			setting.Value, setting.Source = v.value, v.scope
+		} else {
+			setting.Value, setting.Source = setting.Default, SourceDefault
+		}
+
+		// defaults depending on other settings
+		if setting.Source == SourceDefault {
+			switch setting.Env {
+			case "GIT_DUET_AUTHORS_FILE":
+				if setting.Value, err = l.defaultPairsFile(ctx); err != nil {
+					return nil, err
+				}
+			case "GIT_DUET_SET_GIT_USER_CONFIG":
+				setting.Value = defaultSetGitUserConfig(values)
+			}
+		}
+
+		values[setting.Env] = setting.Value
+		resolved = append(resolved, setting)
+	}
+
+	return resolved, nil
+}
+
+// NewConfiguration initializes Configuration from the environment and git
+// config (see Settings)
+// Returns an ErrConfigInvalid error if it cannot parse a setting, e.g. the
+// staleness timeout as an integer or the global var as a bool
 func NewConfiguration() (config *Configuration, err error) {
-	config = &Configuration{
-		Namespace:   getenvDefault("GIT_DUET_CONFIG_NAMESPACE", "duet.env"),
-		EmailLookup: os.Getenv("GIT_DUET_EMAIL_LOOKUP_COMMAND"),
+	return (&Loader{}).Load(context.Background())
+}
+
+// Load is NewConfiguration with the Loader's runner and environment
+func (l *Loader) Load(ctx context.Context) (config *Configuration, err error) {
+	resolved, err := l.Settings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for _, setting := range resolved {
+		values[setting.Env] = setting.Value
 	}
 
-	if config.PairsFile, err = getPairsFile(); err != nil {
+	if config, err = parseSettings(values, l.getenv); err != nil {
+		return nil, withKind(ErrConfigInvalid, err)
+	}
+
+	config.Dir = l.Dir
+	config.CacheDir = DefaultCacheDir(l.getenv)
+	if config.IsCurrentWorkingDirGitRepo, err = l.inRepository(ctx); err != nil {
 		return nil, err
 	}
 
-	cutoff, err := strconv.Atoi(getenvDefault("GIT_DUET_SECONDS_AGO_STALE", "1200"))
+	return config, nil
+}
+
+// ParseConfiguration builds a Configuration from git config keys and values,
+// e.g. duet.coAuthoredBy=true, without reading the environment or git config
+// Missing keys get their defaults, except for the authors file (PairsFile),
+// which keeps a leading ~/ as there is no environment for the home directory
+// Returns an ErrConfigInvalid error if it cannot parse a value
+func ParseConfiguration(values map[string]string) (config *Configuration, err error) {
+	lower := map[string]string{}
+	for key, value := range values {
+		lower[strings.ToLower(key)] = value
+	}
+
+	byEnv := map[string]string{}
+	for _, setting := range settings {
+		value := lower[strings.ToLower(setting.Key)]
+		if value == "" {
+			value = setting.Default
+		}
+		byEnv[setting.Env] = value
+	}
+	if byEnv["GIT_DUET_SET_GIT_USER_CONFIG"] == "" {
+		byEnv["GIT_DUET_SET_GIT_USER_CONFIG"] = defaultSetGitUserConfig(byEnv)
+	}
+
+	if config, err = parseSettings(byEnv, nil); err != nil {
+		return nil, withKind(ErrConfigInvalid, err)
+	}
+	return config, nil
+}
+
+// defaultSetGitUserConfig sets user.name and user.email along with
+// Co-authored-by trailers, since plain git commit is used then
+func defaultSetGitUserConfig(values map[string]string) string {
+	if coAuthoredBy, _ := parseBool(values["GIT_DUET_CO_AUTHORED_BY"]); coAuthoredBy {
+		return "1"
+	}
+	return "0"
+}
+
+// parseSettings builds the Configuration from the values of the settings by
+// environment variable, getenv giving the home directory (see expandHome)
+func parseSettings(values map[string]string, getenv func(key string) string) (config *Configuration, err error) {
+	config = &Configuration{
+		Namespace:   values["GIT_DUET_CONFIG_NAMESPACE"],
+		PairsFile:   expandHome(values["GIT_DUET_AUTHORS_FILE"], getenv),
+		EmailLookup: values["GIT_DUET_EMAIL_LOOKUP_COMMAND"],
+	}
+
+	cutoff, err := strconv.Atoi(values["GIT_DUET_SECONDS_AGO_STALE"])
 	if err != nil {
 		return nil, err
 	}
 
-	if config.Global, err = strconv.ParseBool(getenvDefault("GIT_DUET_GLOBAL", "0")); err != nil {
+	if config.EmailLookupProtocol, err = strconv.Atoi(values["GIT_DUET_EMAIL_LOOKUP_PROTOCOL"]); err != nil {
 		return nil, err
 	}
+	switch config.EmailLookupProtocol {
+	case LookupProtocolArgs, LookupProtocolJSON:
+	default:
+		return nil, fmt.Errorf("unknown email lookup protocol %d (expected %d or %d)", config.EmailLookupProtocol, LookupProtocolArgs, LookupProtocolJSON)
+	}
 
-	if config.RotateAuthor, err = strconv.ParseBool(getenvDefault("GIT_DUET_ROTATE_AUTHOR", "0")); err != nil {
+	if config.EmailLookupTimeout, err = time.ParseDuration(values["GIT_DUET_EMAIL_LOOKUP_TIMEOUT"]); err != nil {
 		return nil, err
 	}
 
-	if config.CoAuthoredBy, err = strconv.ParseBool(getenvDefault("GIT_DUET_CO_AUTHORED_BY", "0")); err != nil {
+	if config.EmailLookupFallback, err = parseBool(values["GIT_DUET_EMAIL_LOOKUP_FALLBACK"]); err != nil {
 		return nil, err
 	}
 
-	if config.DefaultUpdate, err = strconv.ParseBool(getenvDefault("GIT_DUET_DEFAULT_UPDATE", "0")); err != nil {
+	if config.EmailLookupCacheTTL, err = time.ParseDuration(values["GIT_DUET_EMAIL_LOOKUP_CACHE_TTL"]); err != nil {
 		return nil, err
 	}
 
-	if config.AllowMultipleCommitters, err = strconv.ParseBool(getenvDefault("GIT_DUET_ALLOW_MULTIPLE_COMMITTERS", "0")); err != nil {
+	if config.EmailLookupNegativeTTL, err = time.ParseDuration(values["GIT_DUET_EMAIL_LOOKUP_NEGATIVE_TTL"]); err != nil {
 		return nil, err
 	}
 
-	defaultSetGitUserConfig := "0"
-	if config.CoAuthoredBy {
-		defaultSetGitUserConfig = "1"
+	config.StalePolicy = values["GIT_DUET_STALE_POLICY"]
+	switch config.StalePolicy {
+	case StaleBlock, StaleWarn, StalePrompt, StaleSolo:
+	default:
+		return nil, fmt.Errorf("unknown stale policy %s", config.StalePolicy)
+	}
+
+	config.StaleSince = values["GIT_DUET_STALE_SINCE"]
+	switch config.StaleSince {
+	case StaleSinceSet, StaleSinceCommit:
+	default:
+		return nil, fmt.Errorf("unknown stale reference %s (expected %s or %s)", config.StaleSince, StaleSinceSet, StaleSinceCommit)
+	}
+
+	config.ExpireAt = values["GIT_DUET_EXPIRE_AT"]
+	if config.ExpireAt != "" {
+		if _, err = parseClock(config.ExpireAt); err != nil {
+			return nil, err
+		}
 	}
-	if config.SetGitUserConfig, err = strconv.ParseBool(getenvDefault("GIT_DUET_SET_GIT_USER_CONFIG", defaultSetGitUserConfig)); err != nil {
+
+	if config.Global, err = parseBool(values["GIT_DUET_GLOBAL"]); err != nil {
 		return nil, err
 	}
 
-	config.StaleCutoff = time.Duration(cutoff) * time.Second
+	if config.RotateAuthor, err = parseBool(values["GIT_DUET_ROTATE_AUTHOR"]); err != nil {
+		return nil, err
+	}
+
+	config.RotationStrategy = values["GIT_DUET_ROTATION_STRATEGY"]
+
+	if config.RotationCommits, err = strconv.Atoi(values["GIT_DUET_ROTATION_COMMITS"]); err != nil {
+		return nil, err
+	}
+
+	if config.RotationInterval, err = time.ParseDuration(values["GIT_DUET_ROTATION_INTERVAL"]); err != nil {
+		return nil, err
+	}
+
+	if _, err = NewRotationStrategy(config); err != nil {
+		return nil, err
+	}
+
+	config.DriverSource = values["GIT_DUET_DRIVER_SOURCE"]
+
+	if config.CoAuthoredBy, err = parseBool(values["GIT_DUET_CO_AUTHORED_BY"]); err != nil {
+		return nil, err
+	}
+
+	if config.DefaultUpdate, err = parseBool(values["GIT_DUET_DEFAULT_UPDATE"]); err != nil {
+		return nil, err
+	}
+
+	if config.AllowMultipleCommitters, err = parseBool(values["GIT_DUET_ALLOW_MULTIPLE_COMMITTERS"]); err != nil {
+		return nil, err
+	}
+
+	if config.SetGitUserConfig, err = parseBool(values["GIT_DUET_SET_GIT_USER_CONFIG"]); err != nil {
+		return nil, err
+	}
 
-	config.IsCurrentWorkingDirGitRepo, err = checkCwdGitDir()
+	if config.Notes, err = parseBool(values["GIT_DUET_NOTES"]); err != nil {
+		return nil, err
+	}
+
+	config.StaleCutoff = time.Duration(cutoff) * time.Second
 
 	return config, nil
 }
 
-func getPairsFile() (value string, err error) {
-	authorsFile := ".git-authors"
-	defaultAuthorsFile := path.Join(os.Getenv("HOME"), authorsFile)
+// Lookup returns the configured email lookup
+func (c *Configuration) Lookup() EmailLookup {
+	return EmailLookup{
+		Command:  c.EmailLookup,
+		Protocol: c.EmailLookupProtocol,
+		Timeout:  c.EmailLookupTimeout,
+		Fallback: c.EmailLookupFallback,
+		Cache: LookupCache{
+			Dir:         c.CacheDir,
+			TTL:         c.EmailLookupCacheTTL,
+			NegativeTTL: c.EmailLookupNegativeTTL,
+			Refresh:     c.RefreshEmailLookup,
+		},
+	}
+}
 
-	if os.Getenv("GIT_DUET_AUTHORS_FILE") != "" {
-		return os.Getenv("GIT_DUET_AUTHORS_FILE"), nil
+// Pairs reads the configured authors file, determining emails with the
+// configured lookup
+func (c *Configuration) Pairs() (pairs *Pairs, err error) {
+	if pairs, err = NewPairsFromFile(c.PairsFile, c.EmailLookup); err != nil {
+		return nil, err
 	}
+	return pairs.WithEmailLookup(c.Lookup()), nil
+}
+
+// defaultPairsFile returns the authors file of the repository if there is
+// one, otherwise the one in the home directory
+func (l *Loader) defaultPairsFile(ctx context.Context) (value string, err error) {
+	authorsFile := ".git-authors"
+	defaultAuthorsFile := path.Join(l.getenv("HOME"), authorsFile)
 
-	gitDirectory, err := exec.Command("git", "rev-parse", "--show-toplevel").CombinedOutput()
+	output := new(bytes.Buffer)
+	err = run(ctx, l.Runner, &Command{
+		Name:   "git",
+		Args:   []string{"rev-parse", "--show-toplevel"},
+		Dir:    l.Dir,
+		Stdout: output,
+		Stderr: output,
+	})
 	if err != nil {
-		if bytes.Contains(gitDirectory, []byte("Not a git repository")) ||
-			bytes.Contains(gitDirectory, []byte("not a git repository")) {
+		// outside of a repository or in a bare one
+		if bytes.Contains(output.Bytes(), []byte("Not a git repository")) ||
+			bytes.Contains(output.Bytes(), []byte("not a git repository")) ||
+			bytes.Contains(output.Bytes(), []byte("must be run in a work tree")) {
 			return defaultAuthorsFile, nil
 		}
 		return "", err
 	}
 
-	gitDirectoryAuthors := path.Join(strings.TrimSpace(string(gitDirectory)), authorsFile)
+	gitDirectoryAuthors := path.Join(strings.TrimSpace(output.String()), authorsFile)
 	if _, err := os.Stat(gitDirectoryAuthors); err == nil {
 		return gitDirectoryAuthors, nil
 	}
@@ -104,31 +411,73 @@ func getPairsFile() (value string, err error) {
 	return defaultAuthorsFile, nil
 }
 
-func getenvDefault(key, defaultValue string) (value string) {
-	value = os.Getenv(key)
-	if value == "" {
-		value = defaultValue
+// expandHome expands a leading ~/ like git does for paths, to the HOME
+// given by getenv (leaving it as is if getenv is nil)
+func expandHome(filename string, getenv func(key string) string) string {
+	if getenv != nil && strings.HasPrefix(filename, "~/") {
+		return path.Join(getenv("HOME"), filename[2:])
 	}
-
-	return value
+	return filename
 }
 
-func checkCwdGitDir() (hasGitDir bool, err error) {
-	cwd, err := os.Getwd()
-	if err != nil {
-		return false, err
+// parseBool parses booleans the way git config does (true, yes, on, 1 and
+// false, no, off, 0)
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0", "":
+		return false, nil
 	}
-	return checkGitDir(cwd), nil
+	return strconv.ParseBool(value)
 }
 
-func checkGitDir(path string) (hasGitDir bool) {
-	if _, err := os.Stat(path + "/.git"); os.IsNotExist(err) {
-		parent := filepath.Dir(path)
-		if parent == "/" {
-			return false
-		} else {
-			return checkGitDir(parent)
+type gitSetting struct {
+	value string
+	scope string
+}
+
+// readGitSettings returns the duet.* git config values by lower cased key,
+// with the scope they're set in (the most specific one if set in several)
+func (l *Loader) readGitSettings(ctx context.Context) (values map[string]gitSetting, err error) {
+	output := new(bytes.Buffer)
+	cmd := &Command{
+		Name:   "git",
+		Args:   []string{"config", "-z", "--show-scope", "--get-regexp", `^duet\.`},
+		Dir:    l.Dir,
+		Stdout: output,
+		Stderr: l.stderr(),
+	}
+
+	if err = run(ctx, l.Runner, cmd, 1); err != nil {
+		return nil, err
+	}
+
+	values = map[string]gitSetting{}
+	fields := strings.Split(output.String(), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		keyValue := strings.SplitN(fields[i+1], "\n", 2)
+		// a key without a value is a true boolean for git
+		setting := gitSetting{value: "true", scope: fields[i]}
+		if len(keyValue) == 2 {
+			setting.value = keyValue[1]
 		}
+		values[strings.ToLower(keyValue[0])] = setting
+	}
+
+	return values, nil
+}
+
+// inRepository returns whether Dir is in a git repository, as found by git
+// (taking GIT_DIR into account)
+func (l *Loader) inRepository(ctx context.Context) (bool, error) {
+	err := run(ctx, l.Runner, &Command{
+		Name: "git",
+		Args: []string{"rev-parse", "--git-dir"},
+		Dir:  l.Dir,
+	})
+	if code, ok := exitStatus(err); ok && code == 128 {
+		return false, nil
 	}
-	return true
+	return err == nil, err
 }
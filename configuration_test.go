@@ -0,0 +1,42 @@
+package duet_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	duet "github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/duettest"
+)
+
+func TestLoaderOnlyUsesItsEnvironment(t *testing.T) {
+	git := duettest.NewGit("/src/project")
+	git.Handlers["git config"] = func(ctx context.Context, cmd *duet.Command) error {
+		fmt.Fprintln(cmd.Stderr, "warning: from git config")
+		return &duet.ExitError{Code: 1}
+	}
+	env := map[string]string{
+		"HOME":                  "/home/stub",
+		"GIT_DUET_AUTHORS_FILE": "~/authors",
+	}
+
+	stderr := new(bytes.Buffer)
+	loader := &duet.Loader{
+		Dir:    "/src/project",
+		Runner: git,
+		Getenv: func(key string) string { return env[key] },
+		Stderr: stderr,
+	}
+	config, err := loader.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.PairsFile != "/home/stub/authors" {
+		t.Errorf("got authors file %q, want /home/stub/authors", config.PairsFile)
+	}
+	if got := stderr.String(); got != "warning: from git config\n" {
+		t.Errorf("got stderr %q", got)
+	}
+}
@@ -0,0 +1,102 @@
+package duet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const driverSocketTimeout = 2 * time.Second
+
+type driverStatus struct {
+	Driver string `json:"driver"`
+}
+
+// ReadDriver returns the current driver (initials or name) reported by a mob
+// timer at source
+// source is either the path of a JSON file or a Unix socket (optionally
+// prefixed with `unix:`) that writes the JSON and closes the connection, e.g.
+// {"driver": "jd"}
+// Returns an empty string if the source does not exist (no timer running)
+func ReadDriver(source string) (driver string, err error) {
+	var contents []byte
+
+	if strings.HasPrefix(source, "unix:") {
+		contents, err = readDriverSocket(strings.TrimPrefix(source, "unix:"))
+	} else if info, statErr := os.Stat(source); statErr == nil && info.Mode()&os.ModeSocket != 0 {
+		contents, err = readDriverSocket(source)
+	} else {
+		contents, err = ioutil.ReadFile(source)
+	}
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	status := &driverStatus{}
+	if err = json.Unmarshal(contents, status); err != nil {
+		return "", fmt.Errorf("could not parse driver from %s: %+v", source, err)
+	}
+
+	return strings.TrimSpace(status.Driver), nil
+}
+
+func readDriverSocket(path string) (contents []byte, err error) {
+	if _, err = os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("unix", path, driverSocketTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err = conn.SetReadDeadline(time.Now().Add(driverSocketTimeout)); err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(conn)
+}
+
+// FollowDriver makes the person matching driver (by initials or name) the
+// author, keeping the rest of the pairing as committers in rotation order
+// Returns an error if the driver is not part of the pairing
+func (gc *GitConfig) FollowDriver(driver string) (err error) {
+	author, err := gc.GetAuthor()
+	if err != nil {
+		return err
+	}
+	if author == nil {
+		return nil
+	}
+
+	committers, err := gc.GetCommitters()
+	if err != nil {
+		return err
+	}
+
+	everyone := append([]*Pair{author}, committers...)
+	for i, p := range everyone {
+		if p.Initials != driver && !strings.EqualFold(p.Name, driver) {
+			continue
+		}
+		if i == 0 {
+			return nil
+		}
+
+		author, committers = rotate(author, committers, i)
+		if _, err = gc.setAuthor(author); err != nil {
+			return err
+		}
+		return gc.setCommitters(committers)
+	}
+
+	return fmt.Errorf("driver %s is not part of the current pairing", driver)
+}
@@ -0,0 +1,353 @@
+// Package duettest provides an in-memory git for testing code using the duet
+// package without a real git, repository or home directory
+package duettest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	duet "github.com/git-duet/git-duet"
+)
+
+// Git is a duet.Runner faking the git commands git-duet runs:
+//
+//   - git config: reading and writing Local and Global (keys are lower cased,
+//     the default scope reads local then global and writes local)
+//   - git rev-parse --show-toplevel, --git-dir and --git-path: paths in a
+//     repository at TopLevel, failing like outside of one if it's empty
+//   - git rev-parse --quiet --verify HEAD: Head, failing if it's empty
+//   - git notes show and add: Notes, by commit (of any notes ref)
+//   - git interpret-trailers --in-place --trailer: appends the trailer to the
+//     message file (on disk)
+//
+// Other commands run the Handler registered for them, by name (e.g. the email
+// lookup command) or as "git <subcommand>", and succeed without output
+// otherwise for git and fail for anything else
+// Every command run is recorded, see Commands
+type Git struct {
+	Local    map[string]string
+	Global   map[string]string
+	TopLevel string
+	Head     string
+	Notes    map[string]string
+	Handlers map[string]Handler
+
+	mu       sync.Mutex
+	commands []duet.Command
+}
+
+var trailerLine = regexp.MustCompile(`^[\w-]+:\s`)
+
+// Handler runs a command for Git
+type Handler func(ctx context.Context, cmd *duet.Command) error
+
+// NewGit returns a Git with empty configs, in a repository at topLevel
+func NewGit(topLevel string) *Git {
+	return &Git{
+		Local:    map[string]string{},
+		Global:   map[string]string{},
+		TopLevel: topLevel,
+		Notes:    map[string]string{},
+		Handlers: map[string]Handler{},
+	}
+}
+
+// Commands returns the commands run so far
+func (g *Git) Commands() []duet.Command {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]duet.Command{}, g.commands...)
+}
+
+// Get returns the value of a key in the local config, or in the global one if
+// it's not set locally
+func (g *Git) Get(key string) (value string, ok bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.get("", strings.ToLower(key))
+}
+
+// Run runs the command, see Git
+func (g *Git) Run(ctx context.Context, cmd *duet.Command) error {
+	g.mu.Lock()
+	g.commands = append(g.commands, *cmd)
+	g.mu.Unlock()
+
+	if cmd.Name != "git" {
+		if handler, ok := g.Handlers[cmd.Name]; ok {
+			return handler(ctx, cmd)
+		}
+		return fmt.Errorf("duettest: no handler for %s", cmd.Name)
+	}
+
+	if len(cmd.Args) == 0 {
+		return &duet.ExitError{Code: 1}
+	}
+	if handler, ok := g.Handlers["git "+cmd.Args[0]]; ok {
+		return handler(ctx, cmd)
+	}
+
+	switch cmd.Args[0] {
+	case "config":
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return g.config(cmd, cmd.Args[1:])
+	case "rev-parse":
+		return g.revParse(cmd, cmd.Args[1:])
+	case "interpret-trailers":
+		return interpretTrailers(cmd, cmd.Args[1:])
+	case "notes":
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return g.notes(cmd, cmd.Args[1:])
+	}
+	return nil
+}
+
+func (g *Git) config(cmd *duet.Command, args []string) error {
+	var scope, action string
+	var nul, showScope bool
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "--global", "--local":
+			scope = args[0][2:]
+		case "-z":
+			nul = true
+		case "--show-scope":
+			showScope = true
+		case "--get-regexp", "--unset-all":
+			action = args[0]
+		default:
+			return usage(cmd, "config", args[0])
+		}
+		args = args[1:]
+	}
+
+	switch {
+	case action == "--get-regexp" && len(args) == 1:
+		return g.getRegexp(cmd, scope, args[0], nul, showScope)
+	case action == "--unset-all" && len(args) == 1:
+		return g.unset(scope, strings.ToLower(args[0]))
+	case action == "" && len(args) == 1:
+		value, ok := g.get(scope, strings.ToLower(args[0]))
+		if !ok {
+			return &duet.ExitError{Code: 1}
+		}
+		fmt.Fprintln(stdout(cmd), value)
+		return nil
+	case action == "" && len(args) == 2:
+		g.scope(scope)[strings.ToLower(args[0])] = args[1]
+		return nil
+	}
+	return usage(cmd, "config", strings.Join(args, " "))
+}
+
+// scope returns the config written to in a scope
+func (g *Git) scope(scope string) map[string]string {
+	if scope == "global" {
+		return g.Global
+	}
+	return g.Local
+}
+
+func (g *Git) get(scope, key string) (value string, ok bool) {
+	if scope == "" {
+		if value, ok = g.Local[key]; ok {
+			return value, ok
+		}
+		value, ok = g.Global[key]
+		return value, ok
+	}
+	value, ok = g.scope(scope)[key]
+	return value, ok
+}
+
+func (g *Git) unset(scope, key string) error {
+	config := g.scope(scope)
+	if _, ok := config[key]; !ok {
+		return &duet.ExitError{Code: 5}
+	}
+	delete(config, key)
+	return nil
+}
+
+// getRegexp lists the matching keys like git does, from the least specific
+// scope to the most specific one
+func (g *Git) getRegexp(cmd *duet.Command, scope, pattern string, nul, showScope bool) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return usage(cmd, "config", pattern)
+	}
+
+	scopes := []string{"global", "local"}
+	if scope != "" {
+		scopes = []string{scope}
+	}
+
+	found := false
+	for _, s := range scopes {
+		config := g.scope(s)
+		var keys []string
+		for key := range config {
+			if re.MatchString(key) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			found = true
+			var entry string
+			switch {
+			case nul && showScope:
+				entry = fmt.Sprintf("%s\x00%s\n%s\x00", s, key, config[key])
+			case nul:
+				entry = fmt.Sprintf("%s\n%s\x00", key, config[key])
+			case showScope:
+				entry = fmt.Sprintf("%s\t%s %s\n", s, key, config[key])
+			default:
+				entry = fmt.Sprintf("%s %s\n", key, config[key])
+			}
+			io.WriteString(stdout(cmd), entry)
+		}
+	}
+
+	if !found {
+		return &duet.ExitError{Code: 1}
+	}
+	return nil
+}
+
+func (g *Git) revParse(cmd *duet.Command, args []string) error {
+	var output string
+	switch {
+	case len(args) == 1 && args[0] == "--show-toplevel":
+		output = g.TopLevel
+	case len(args) == 1 && args[0] == "--git-dir":
+		output = filepath.Join(g.TopLevel, ".git")
+	case len(args) == 2 && args[0] == "--git-path":
+		output = filepath.Join(g.TopLevel, ".git", args[1])
+	case len(args) == 3 && args[0] == "--quiet" && args[1] == "--verify" && args[2] == "HEAD":
+		if g.Head == "" {
+			return &duet.ExitError{Code: 1}
+		}
+		output = g.Head
+	default:
+		return usage(cmd, "rev-parse", strings.Join(args, " "))
+	}
+	if g.TopLevel == "" {
+		fmt.Fprintln(stderr(cmd), "fatal: not a git repository (or any of the parent directories): .git")
+		return &duet.ExitError{Code: 128}
+	}
+	fmt.Fprintln(stdout(cmd), output)
+	return nil
+}
+
+func (g *Git) notes(cmd *duet.Command, args []string) error {
+	if len(args) >= 2 && args[0] == "--ref" {
+		args = args[2:]
+	}
+	if len(args) == 0 {
+		return usage(cmd, "notes", "")
+	}
+
+	commit := args[len(args)-1]
+	switch args[0] {
+	case "show":
+		note, ok := g.Notes[commit]
+		if !ok {
+			fmt.Fprintf(stderr(cmd), "error: no note found for object %s.\n", commit)
+			return &duet.ExitError{Code: 1}
+		}
+		io.WriteString(stdout(cmd), note)
+		return nil
+	case "add":
+		var text []byte
+		if cmd.Stdin != nil {
+			var err error
+			if text, err = ioutil.ReadAll(cmd.Stdin); err != nil {
+				return err
+			}
+		}
+		g.Notes[commit] = string(text)
+		return nil
+	}
+	return usage(cmd, "notes", args[0])
+}
+
+// interpretTrailers appends trailers to a message, starting a trailers block
+// unless the last paragraph already is one
+func interpretTrailers(cmd *duet.Command, args []string) error {
+	var trailers []string
+	var file string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--in-place":
+		case args[i] == "--trailer" && i+1 < len(args):
+			i++
+			trailers = append(trailers, args[i])
+		case file == "" && !strings.HasPrefix(args[i], "-"):
+			file = args[i]
+		default:
+			return usage(cmd, "interpret-trailers", args[i])
+		}
+	}
+	if file == "" {
+		return usage(cmd, "interpret-trailers", "missing file")
+	}
+	if !filepath.IsAbs(file) && cmd.Dir != "" {
+		file = filepath.Join(cmd.Dir, file)
+	}
+
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	message := strings.TrimRight(string(contents), "\n")
+	paragraphs := strings.Split(message, "\n\n")
+	// the first paragraph is the subject, never trailers
+	if len(paragraphs) < 2 || !isTrailerBlock(paragraphs[len(paragraphs)-1]) {
+		message += "\n"
+	}
+	for _, trailer := range trailers {
+		message += "\n" + trailer
+	}
+
+	return ioutil.WriteFile(file, []byte(message+"\n"), 0644)
+}
+
+func isTrailerBlock(paragraph string) bool {
+	for _, line := range strings.Split(paragraph, "\n") {
+		if !trailerLine.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
+
+func usage(cmd *duet.Command, subcommand, arg string) error {
+	fmt.Fprintf(stderr(cmd), "duettest: unsupported git %s argument %s\n", subcommand, arg)
+	return &duet.ExitError{Code: 129}
+}
+
+func stdout(cmd *duet.Command) io.Writer {
+	if cmd.Stdout == nil {
+		return ioutil.Discard
+	}
+	return cmd.Stdout
+}
+
+func stderr(cmd *duet.Command) io.Writer {
+	if cmd.Stderr == nil {
+		return ioutil.Discard
+	}
+	return cmd.Stderr
+}
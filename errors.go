@@ -0,0 +1,82 @@
+package duet
+
+import "errors"
+
+// Kinds of errors the commands tell apart by exit code, check for them with
+// errors.Is
+var (
+	ErrUnknownInitials    = errors.New("unknown initials")
+	ErrAmbiguousInitials  = errors.New("ambiguous initials")
+	ErrAuthorsFileInvalid = errors.New("invalid authors file")
+	ErrAuthorNotSet       = errors.New("git-author not set")
+	ErrStale              = errors.New("git duet settings are stale")
+	ErrConfigInvalid      = errors.New("invalid configuration")
+)
+
+// Exit codes of the commands (see README.md), anything else is either 1 or
+// the exit code of a failed git command
+const (
+	ExitUnknownInitials    = 86
+	ExitAmbiguousInitials  = 87
+	ExitAuthorsFileInvalid = 88
+	ExitAuthorNotSet       = 89
+	ExitStale              = 90
+	ExitConfigInvalid      = 91
+)
+
+var exitCodes = []struct {
+	err  error
+	code int
+}{
+	{ErrUnknownInitials, ExitUnknownInitials},
+	{ErrAmbiguousInitials, ExitAmbiguousInitials},
+	{ErrAuthorsFileInvalid, ExitAuthorsFileInvalid},
+	{ErrAuthorNotSet, ExitAuthorNotSet},
+	{ErrStale, ExitStale},
+	{ErrConfigInvalid, ExitConfigInvalid},
+}
+
+// ExitCode returns the exit code for an error: the code of its kind, the exit
+// code of git if it failed, 1 otherwise (0 for nil)
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	for _, e := range exitCodes {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+
+	if code, ok := exitStatus(err); ok && code > 0 {
+		return code
+	}
+
+	return 1
+}
+
+// kindError gives an error one of the kinds above while keeping its message
+type kindError struct {
+	kind error
+	err  error
+}
+
+func withKind(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
@@ -0,0 +1,171 @@
+package duet_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	duet "github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/duettest"
+)
+
+const authorsFile = `authors:
+  jd: Jane Doe; jane
+  fb: Frances Bar
+email:
+  domain: awesometown.local
+email_addresses:
+  fb: frances@awesometown.local
+`
+
+func readPairs() *duet.Pairs {
+	pairs, err := duet.ReadPairs(strings.NewReader(authorsFile), "")
+	if err != nil {
+		panic(err)
+	}
+	return pairs
+}
+
+func ExampleParseConfiguration() {
+	config, err := duet.ParseConfiguration(map[string]string{
+		"duet.coAuthoredBy": "true",
+		"duet.rotateAuthor": "true",
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(config.Namespace, config.CoAuthoredBy, config.SetGitUserConfig, config.RotationStrategy)
+	// Output: duet.env true true round-robin
+}
+
+func ExampleReadPairs() {
+	pairs, err := duet.ReadPairs(strings.NewReader(authorsFile), "")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	for _, initials := range []string{"jd", "fb"} {
+		pair, err := pairs.ByInitials(initials)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Println(pair.Initials, pair.Name, pair.Email)
+	}
+	// Output:
+	// jd Jane Doe jane@awesometown.local
+	// fb Frances Bar frances@awesometown.local
+}
+
+func ExamplePairs_WithContext() {
+	git := duettest.NewGit("/src/project")
+	git.Handlers["lookup-email"] = func(ctx context.Context, cmd *duet.Command) error {
+		fmt.Fprintf(cmd.Stdout, "%s@lookup.local\n", cmd.Args[2])
+		return nil
+	}
+
+	pairs, err := duet.ReadPairs(strings.NewReader(authorsFile), "lookup-email")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// the email lookup is given up on once ctx is done
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	jd, err := pairs.WithRunner(git).WithContext(ctx).ByInitials("jd")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(jd.Email)
+	// Output: jane@lookup.local
+}
+
+func ExampleConfiguration_CommitIdentity() {
+	config, err := duet.ParseConfiguration(nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	pairs := readPairs()
+	jd, _ := pairs.ByInitials("jd")
+	fb, _ := pairs.ByInitials("fb")
+
+	identity := config.CommitIdentity(jd, []*duet.Pair{fb}, true)
+	fmt.Println(strings.Join(identity.Env, "\n"))
+	fmt.Println(identity.Args)
+	// Output:
+	// GIT_AUTHOR_NAME=Jane Doe
+	// GIT_AUTHOR_EMAIL=jane@awesometown.local
+	// GIT_COMMITTER_NAME=Frances Bar
+	// GIT_COMMITTER_EMAIL=frances@awesometown.local
+	// [--signoff]
+}
+
+func ExampleTrailers() {
+	pairs := readPairs()
+	jd, _ := pairs.ByInitials("jd")
+	fb, _ := pairs.ByInitials("fb")
+
+	for _, trailer := range duet.Trailers(duet.CoAuthoredBy, []*duet.Pair{jd, fb}) {
+		fmt.Println(trailer)
+	}
+	// Output:
+	// Co-authored-by: Jane Doe <jane@awesometown.local>
+	// Co-authored-by: Frances Bar <frances@awesometown.local>
+}
+
+func ExampleGitConfig_dir() {
+	git := duettest.NewGit("/src/other")
+	jd, _ := readPairs().ByInitials("jd")
+
+	// git runs in Dir rather than in the current directory
+	gitConfig := &duet.GitConfig{Namespace: "duet.env", Dir: "/src/other", Runner: git}
+	if err := gitConfig.SetAuthor(jd); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	cmd := git.Commands()[0]
+	fmt.Println(cmd.Dir, strings.Join(cmd.Args, " "))
+	// Output: /src/other config duet.env.git-author-initials jd
+}
+
+func ExampleReadHistory() {
+	git := duettest.NewGit("/src/project")
+	git.Handlers["git log"] = func(ctx context.Context, cmd *duet.Command) error {
+		fields := []string{
+			"0123456789abcdef0123456789abcdef01234567", "1500000000",
+			"Jane Doe", "jane@awesometown.local",
+			"Jane Doe", "jane@awesometown.local",
+			"Add the thing",
+			"Co-authored-by: Frances Bar <frances@awesometown.local>\n",
+		}
+		fmt.Fprintf(cmd.Stdout, "%s\x00", strings.Join(fields, "\x1f"))
+		return nil
+	}
+
+	commits, err := duet.ReadHistory(context.Background(), git, "/src/project", nil, "--since=1.week")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	for _, commit := range commits {
+		fmt.Println(commit.Subject)
+		for _, identity := range commit.Participants() {
+			fmt.Println(identity)
+		}
+	}
+	// Output:
+	// Add the thing
+	// Jane Doe <jane@awesometown.local>
+	// Frances Bar <frances@awesometown.local>
+}
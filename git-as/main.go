@@ -1,11 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 
 	"github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/internal/cmd"
 	"github.com/pborman/getopt"
 )
 
@@ -18,14 +19,18 @@ var (
 
 func main() {
 	var (
-		quiet   = getopt.BoolLong("quiet", 'q', "Silence output")
-		global  = getopt.BoolLong("global", 'g', "Change global config")
-		help    = getopt.BoolLong("help", 'h', "Help")
-		version = getopt.BoolLong("version", 'v', "Version")
-		show    = getopt.BoolLong("show", 's', "Show")
+		quiet     = getopt.BoolLong("quiet", 'q', "Silence output")
+		global    = getopt.BoolLong("global", 'g', "Change global config")
+		help      = getopt.BoolLong("help", 'h', "Help")
+		version   = getopt.BoolLong("version", 'v', "Version")
+		show      = getopt.BoolLong("show", 's', "Show")
+		recurse   = getopt.BoolLong("recurse-submodules", 0, "Also set the author in the initialized submodules")
+		refresh   = getopt.BoolLong("refresh", 0, "Run the email lookup again instead of using cached emails")
+		directory = cmd.DirOption(nil)
 	)
 
 	getopt.Parse()
+	dir := string(*directory)
 
 	if *help {
 		getopt.Usage()
@@ -37,13 +42,14 @@ func main() {
 		os.Exit(0)
 	}
 
-	configuration, err := duet.NewConfiguration()
+	configuration, err := (&duet.Loader{Dir: dir}).Load(context.Background())
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
+	configuration.RefreshEmailLookup = *refresh
 
-	gitConfig := &duet.GitConfig{Namespace: configuration.Namespace, SetUserConfig: configuration.SetGitUserConfig}
+	gitConfig := &duet.GitConfig{Namespace: configuration.Namespace, SetUserConfig: configuration.SetGitUserConfig, Dir: dir}
 	if *global || configuration.Global {
 		gitConfig.Scope = duet.Global
 	}
@@ -52,53 +58,53 @@ func main() {
 		author, err := gitConfig.GetAuthor()
 		if err != nil {
 			fmt.Println(err)
-			os.Exit(1)
+			os.Exit(duet.ExitCode(err))
 		}
 		committers, err := gitConfig.GetCommitters()
 		if err != nil {
 			fmt.Println(err)
-			os.Exit(1)
+			os.Exit(duet.ExitCode(err))
 		}
 
 		printAuthor(author)
 		printNextCommitter(committers)
 		if configuration.CoAuthoredBy {
-			installHook("prepare-commit-msg")
+			installHook(dir, "prepare-commit-msg", *recurse)
 			// SetAuthor is needed in case neither GIT_DUET_CO_AUTHORED_BY nor GIT_DUET_SET_GIT_USER_CONFIG was set previously
 			if err = gitConfig.SetAuthor(author); err != nil {
 				fmt.Println(err)
-				os.Exit(1)
+				os.Exit(duet.ExitCode(err))
 			}
-			if configuration.RotateAuthor {
-				installHook("post-commit")
+			if configuration.RotateAuthor || configuration.StaleSince == duet.StaleSinceCommit || configuration.Notes {
+				installHook(dir, "post-commit", *recurse)
 			}
 		}
 		os.Exit(0)
 	}
 
-	pairs, err := duet.NewPairsFromFile(configuration.PairsFile, configuration.EmailLookup)
+	pairs, err := configuration.Pairs()
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(0)
+		os.Exit(duet.ExitCode(err))
 	}
 
-	author, err := pairs.ByInitials(getopt.Arg(0))
+	author, err := pairs.Resolve(getopt.Arg(0))
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(86)
+		os.Exit(duet.ExitCode(err))
 	}
 	if err = gitConfig.SetAuthor(author); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
 
 	var committers []*duet.Pair
 
 	for _, initials := range getopt.Args()[1:] {
-		committer, err := pairs.ByInitials(initials)
+		committer, err := pairs.Resolve(initials)
 		if err != nil {
 			fmt.Println(err)
-			os.Exit(86)
+			os.Exit(duet.ExitCode(err))
 		}
 
 		committers = append(committers, committer)
@@ -106,7 +112,14 @@ func main() {
 
 	if err = gitConfig.SetCommitters(committers...); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	if len(committers) == 0 {
+		if err = gitConfig.SetSoloist(author); err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
 	}
 
 	if !*quiet {
@@ -114,12 +127,31 @@ func main() {
 		printNextCommitter(committers)
 	}
 
+	// submodules read the global config too, only a local author needs
+	// copying
+	code := 0
+	if *recurse && gitConfig.Scope != duet.Global {
+		code = cmd.SetSubmodules(context.Background(), gitConfig, func(submodule *duet.GitConfig) error {
+			if err := submodule.SetAuthor(author); err != nil {
+				return err
+			}
+			if err := submodule.SetCommitters(committers...); err != nil {
+				return err
+			}
+			if len(committers) == 0 {
+				return submodule.SetSoloist(author)
+			}
+			return nil
+		})
+	}
+
 	if configuration.CoAuthoredBy {
-		installHook("prepare-commit-msg")
-		if configuration.RotateAuthor {
-			installHook("post-commit")
+		installHook(dir, "prepare-commit-msg", *recurse)
+		if configuration.RotateAuthor || configuration.StaleSince == duet.StaleSinceCommit || configuration.Notes {
+			installHook(dir, "post-commit", *recurse)
 		}
 	}
+	os.Exit(code)
 }
 
 func printAuthor(author *duet.Pair) {
@@ -140,14 +172,11 @@ func printNextCommitter(committers []*duet.Pair) {
 	fmt.Printf("GIT_COMMITTER_EMAIL='%s'\n", committers[0].Email)
 }
 
-func installHook(hookType string) {
-	cmd := exec.Command("git-duet-install-hook", hookType)
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-
-	err := cmd.Run()
-	if err != nil {
+// installHook installs a hook in the repository containing dir, and in its
+// submodules if recurse is set
+func installHook(dir, hookType string, recurse bool) {
+	if err := cmd.InstallHook(context.Background(), duet.ExecRunner{}, dir, hookType, recurse); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
 }
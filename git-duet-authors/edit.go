@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/internal/cmd"
+	"github.com/pborman/getopt"
+)
+
+func addAuthor(dir cmd.Dir, args []string) {
+	set := getopt.New()
+	var (
+		email    = set.StringLong("email", 'e', "", "Email address (listed under email_addresses)")
+		username = set.StringLong("username", 'u', "", "Username used to build the email address")
+		help     = set.BoolLong("help", 'h', "Help")
+	)
+
+	set.SetProgram("git duet authors add")
+	set.SetParameters("<initials> <name>")
+	params := parseInterspersed(set, args)
+
+	if *help {
+		set.PrintUsage(os.Stdout)
+		os.Exit(0)
+	}
+
+	if len(params) < 2 {
+		set.PrintUsage(os.Stdout)
+		os.Exit(1)
+	}
+
+	configuration, authorsFile := loadAuthorsFile(dir)
+	if err := authorsFile.Add(params[0], strings.Join(params[1:], " "), *username, *email, configuration.Lookup()); err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	saveAuthorsFile(authorsFile)
+}
+
+func removeAuthor(dir cmd.Dir, args []string) {
+	set := getopt.New()
+	help := set.BoolLong("help", 'h', "Help")
+
+	set.SetProgram("git duet authors remove")
+	set.SetParameters("<initials>")
+	params := parseInterspersed(set, args)
+
+	if *help {
+		set.PrintUsage(os.Stdout)
+		os.Exit(0)
+	}
+
+	if len(params) != 1 {
+		set.PrintUsage(os.Stdout)
+		os.Exit(1)
+	}
+
+	_, authorsFile := loadAuthorsFile(dir)
+	if err := authorsFile.Remove(params[0]); err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	saveAuthorsFile(authorsFile)
+}
+
+func renameAuthor(dir cmd.Dir, args []string) {
+	set := getopt.New()
+	help := set.BoolLong("help", 'h', "Help")
+
+	set.SetProgram("git duet authors rename")
+	set.SetParameters("<initials> <new initials>")
+	params := parseInterspersed(set, args)
+
+	if *help {
+		set.PrintUsage(os.Stdout)
+		os.Exit(0)
+	}
+
+	if len(params) != 2 {
+		set.PrintUsage(os.Stdout)
+		os.Exit(1)
+	}
+
+	configuration, authorsFile := loadAuthorsFile(dir)
+	if err := authorsFile.Rename(params[0], params[1], configuration.Lookup()); err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	saveAuthorsFile(authorsFile)
+}
+
+func loadAuthorsFile(dir cmd.Dir) (*duet.Configuration, *duet.AuthorsFile) {
+	configuration, err := (&duet.Loader{Dir: string(dir)}).Load(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	authorsFile, err := duet.LoadAuthorsFile(configuration.PairsFile)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	return configuration, authorsFile
+}
+
+func saveAuthorsFile(authorsFile *duet.AuthorsFile) {
+	if err := authorsFile.Save(); err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+}
+
+// parseInterspersed parses options given before, between or after the
+// parameters and returns the parameters
+// A lone - is a parameter, as is everything after --
+func parseInterspersed(set *getopt.Set, args []string) (params []string) {
+	var trailing []string
+	for i, arg := range args {
+		if i > 0 && arg == "--" {
+			args, trailing = args[:i], args[i+1:]
+			break
+		}
+	}
+
+	for {
+		set.Parse(args)
+		rest := set.Args()
+
+		i := 0
+		for i < len(rest) && (rest[i] == "-" || !strings.HasPrefix(rest[i], "-")) {
+			i++
+		}
+		params = append(params, rest[:i]...)
+
+		// stop unless getopt made progress, it leaves what it can't parse
+		if i == len(rest) || len(rest[i:]) >= len(args)-1 {
+			return append(append(params, rest[i:]...), trailing...)
+		}
+		args = append([]string{args[0]}, rest[i:]...)
+	}
+}
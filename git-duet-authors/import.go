@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/internal/cmd"
+	"github.com/pborman/getopt"
+	"gopkg.in/yaml.v2"
+)
+
+// person is somebody found in history with all the emails they used, most
+// recent first
+type person struct {
+	name   string
+	emails []string
+}
+
+// webCommitter commits merges and edits made through the GitHub UI, it is
+// never a person
+const webCommitter = "noreply@github.com"
+
+func importAuthors(dir cmd.Dir, args []string) {
+	set := getopt.New()
+	var (
+		fromMailmap = set.StringLong("from-mailmap", 0, "", "Import the proper identities from a .mailmap file instead of git log")
+		output      = set.StringLong("output", 'o', "", "Authors file to write (- for standard output, defaults to .git-authors in the repository)")
+		force       = set.BoolLong("force", 'f', "Overwrite an existing authors file")
+		yes         = set.BoolLong("yes", 'y', "Accept the proposed initials without reviewing them")
+		help        = set.BoolLong("help", 'h', "Help")
+	)
+
+	set.SetProgram("git duet authors import")
+	set.SetParameters("[<revision range>]")
+	params := parseInterspersed(set, args)
+
+	if *help {
+		set.PrintUsage(os.Stdout)
+		os.Exit(0)
+	}
+
+	var people []*person
+	var err error
+	if *fromMailmap != "" {
+		people, err = peopleFromMailmap(dir.Path(*fromMailmap))
+	} else {
+		people, err = peopleFromHistory(dir, params...)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	pairs, others := proposeAuthors(people)
+	reportDuplicates(os.Stderr, pairs)
+	if !*yes {
+		if pairs, err = review(os.Stdin, os.Stderr, pairs, others); err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+	}
+
+	writeAuthors(dir, pairs, others, *output, *force)
+}
+
+func peopleFromMailmap(filename string) (people []*person, err error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := duet.ParseMailmap(bytes.NewReader(contents))
+	if err != nil {
+		return nil, err
+	}
+
+	byEmail := map[string]*person{}
+	for _, entry := range entries {
+		name := entry.Proper.Name
+		if name == "" {
+			name = entry.Commit.Name
+		}
+		email := strings.ToLower(entry.Proper.Email)
+		if name == "" || byEmail[email] != nil {
+			continue
+		}
+		byEmail[email] = &person{name: name, emails: []string{entry.Proper.Email}}
+		people = append(people, byEmail[email])
+	}
+
+	return people, nil
+}
+
+// peopleFromHistory groups the identities in git log into people, treating
+// identities that share an email as the same person (a shared name, like
+// "root", doesn't make them one, see reportDuplicates)
+// Each person gets their most recent full name (the one with the most words)
+// The history of every branch is read unless revisions are given
+func peopleFromHistory(dir cmd.Dir, revisions ...string) (people []*person, err error) {
+	if len(revisions) == 0 {
+		revisions = []string{"--all"}
+	}
+	commits, err := duet.ReadHistory(context.Background(), duet.ExecRunner{}, string(dir), os.Stderr, revisions...)
+	if err != nil {
+		return nil, err
+	}
+
+	var identities []duet.Identity
+	seen := map[duet.Identity]bool{}
+	for _, commit := range commits {
+		for _, identity := range commit.Participants() {
+			identity.Name = strings.Join(strings.Fields(identity.Name), " ")
+			if !seen[identity] && strings.ToLower(identity.Email) != webCommitter {
+				seen[identity] = true
+				identities = append(identities, identity)
+			}
+		}
+	}
+
+	// union-find over identities linked by email
+	parent := make([]int, len(identities))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	byKey := map[string]int{}
+	for i, identity := range identities {
+		key := strings.ToLower(identity.Email)
+		if j, ok := byKey[key]; ok {
+			parent[find(i)] = find(j)
+		} else {
+			byKey[key] = i
+		}
+	}
+
+	// history is newest first, so the first identity of a group is the most
+	// recent one
+	byRoot := map[int]*person{}
+	for i, identity := range identities {
+		root := find(i)
+		p, ok := byRoot[root]
+		if !ok {
+			p = &person{name: identity.Name}
+			byRoot[root] = p
+			people = append(people, p)
+		}
+
+		// prefer full names over nicknames
+		if len(strings.Fields(identity.Name)) > len(strings.Fields(p.name)) {
+			p.name = identity.Name
+		}
+
+		known := false
+		for _, email := range p.emails {
+			known = known || strings.EqualFold(email, identity.Email)
+		}
+		if !known {
+			p.emails = append(p.emails, identity.Email)
+		}
+	}
+
+	return people, nil
+}
+
+// proposeAuthors assigns initials to people in order of their names and
+// returns the other emails of each (by initials)
+func proposeAuthors(people []*person) (pairs []*duet.Pair, others map[string][]string) {
+	sort.SliceStable(people, func(i, j int) bool {
+		return strings.ToLower(people[i].name) < strings.ToLower(people[j].name)
+	})
+
+	taken := map[string]bool{}
+	others = map[string][]string{}
+	for _, p := range people {
+		initials := duet.ProposeInitials(p.name, taken)
+		taken[initials] = true
+		pairs = append(pairs, &duet.Pair{Initials: initials, Name: p.name, Email: p.emails[0]})
+		if len(p.emails) > 1 {
+			others[initials] = p.emails[1:]
+		}
+	}
+
+	return pairs, others
+}
+
+// reportDuplicates lists the proposed authors sharing a name, who may be the
+// same person using unrelated emails (or different people, e.g. root)
+func reportDuplicates(out io.Writer, pairs []*duet.Pair) {
+	byName := map[string][]*duet.Pair{}
+	var names []string
+	for _, p := range pairs {
+		name := strings.ToLower(p.Name)
+		if len(byName[name]) == 0 {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], p)
+	}
+
+	for _, name := range names {
+		if len(byName[name]) < 2 {
+			continue
+		}
+		var entries []string
+		for _, p := range byName[name] {
+			entries = append(entries, fmt.Sprintf("%s <%s>", p.Initials, p.Email))
+		}
+		fmt.Fprintf(out, "possible duplicates, answer the initials of the first to merge them: %s\n", strings.Join(entries, ", "))
+	}
+}
+
+// review asks about each proposed author, the answer can be empty to accept
+// the proposal, new initials, - to leave the person out or the initials of an
+// accepted author with the same name to merge them
+// Running out of input accepts the remaining proposals
+func review(in io.Reader, out io.Writer, proposed []*duet.Pair, others map[string][]string) (pairs []*duet.Pair, err error) {
+	taken := map[string]bool{}
+	for _, p := range proposed {
+		taken[p.Initials] = true
+	}
+
+	reader := bufio.NewReader(in)
+	for _, p := range proposed {
+		fmt.Fprintf(out, "%s <%s>", p.Name, p.Email)
+		if len(others[p.Initials]) > 0 {
+			fmt.Fprintf(out, " (also %s)", strings.Join(others[p.Initials], ", "))
+		}
+
+		for {
+			fmt.Fprintf(out, "\ninitials [%s, - to skip]: ", p.Initials)
+			answer, err := reader.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			answer = strings.TrimSpace(answer)
+
+			if answer == "" || answer == p.Initials {
+				pairs = append(pairs, p)
+			} else if answer == "-" {
+				delete(taken, p.Initials)
+			} else if !duet.ValidInitials(answer) {
+				fmt.Fprintf(out, "invalid initials %s", answer)
+				continue
+			} else if same := accepted(pairs, answer); same != nil && strings.EqualFold(same.Name, p.Name) {
+				// a possible duplicate (see reportDuplicates) is merged
+				delete(taken, p.Initials)
+				others[answer] = append(append(others[answer], p.Email), others[p.Initials]...)
+				delete(others, p.Initials)
+			} else if taken[answer] {
+				fmt.Fprintf(out, "initials %s are already taken", answer)
+				continue
+			} else {
+				delete(taken, p.Initials)
+				taken[answer] = true
+				others[answer] = others[p.Initials]
+				delete(others, p.Initials)
+				p.Initials = answer
+				pairs = append(pairs, p)
+			}
+			break
+		}
+	}
+	fmt.Fprintln(out)
+
+	return pairs, nil
+}
+
+// accepted returns the accepted author with the given initials, nil if none
+func accepted(pairs []*duet.Pair, initials string) *duet.Pair {
+	for _, p := range pairs {
+		if p.Initials == initials {
+			return p
+		}
+	}
+	return nil
+}
+
+func writeAuthors(dir cmd.Dir, pairs []*duet.Pair, others map[string][]string, output string, force bool) {
+	switch output {
+	case "":
+		output = defaultAuthorsFile(dir)
+	case "-":
+	default:
+		output = dir.Path(output)
+	}
+
+	var contents bytes.Buffer
+	if err := duet.WriteAuthorsFile(&contents, pairs); err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+	annotated := annotate(contents.Bytes(), others)
+
+	if output == "-" {
+		os.Stdout.Write(annotated)
+		return
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(output, flags, 0644)
+	if os.IsExist(err) {
+		fmt.Printf("%s already exists, use --force to overwrite it\n", output)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+	defer file.Close()
+
+	if _, err = file.Write(annotated); err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	fmt.Printf("git-duet-authors: wrote %d authors to %s\n", len(pairs), output)
+}
+
+// annotate lists the other emails of each person as a comment next to their
+// entry in `email_addresses` (there is nowhere else to keep them)
+func annotate(contents []byte, others map[string][]string) []byte {
+	var out bytes.Buffer
+	section := ""
+	for _, line := range strings.SplitAfter(string(contents), "\n") {
+		if !strings.HasPrefix(line, " ") {
+			section = strings.TrimSpace(line)
+		} else if section == "email_addresses:" {
+			initials := unquoteKey(strings.TrimSpace(strings.SplitN(line, ":", 2)[0]))
+			if len(others[initials]) > 0 {
+				line = strings.TrimRight(line, "\n") + " # also " + strings.Join(others[initials], ", ") + "\n"
+			}
+		}
+		out.WriteString(line)
+	}
+	return out.Bytes()
+}
+
+// unquoteKey returns the initials of a key of the written authors file, which
+// quotes those YAML would read as something else (e.g. "on" or "no")
+func unquoteKey(key string) string {
+	var unquoted string
+	if strings.HasPrefix(key, "\"") || strings.HasPrefix(key, "'") {
+		if err := yaml.Unmarshal([]byte(key), &unquoted); err == nil {
+			return unquoted
+		}
+	}
+	return key
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/internal/cmd"
+	"github.com/pborman/getopt"
+)
+
+const usage = `usage: git duet authors <command> [<options>]
+
+commands:
+  add       add an author to the authors file
+  remove    remove an author from the authors file
+  rename    change the initials of an author in the authors file
+  import    write an authors file from the identities in git history (or a
+            .mailmap with --from-mailmap)
+`
+
+func main() {
+	set := getopt.New()
+	var (
+		complete  = set.BoolLong("complete", 0, "Print the initials and teams for shell completion")
+		help      = set.BoolLong("help", 'h', "Help")
+		directory = cmd.DirOption(set)
+	)
+
+	// options of the commands follow them
+	set.Parse(os.Args)
+	dir := *directory
+
+	if *help {
+		fmt.Print(usage)
+		os.Exit(0)
+	}
+	if *complete {
+		completeAuthors(dir)
+		os.Exit(0)
+	}
+
+	if set.NArgs() < 1 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	switch args := set.Args(); args[0] {
+	case "add":
+		addAuthor(dir, args)
+	case "remove":
+		removeAuthor(dir, args)
+	case "rename":
+		renameAuthor(dir, args)
+	case "import":
+		importAuthors(dir, args)
+	default:
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+}
+
+// completeAuthors prints the initials and teams of the authors file with a
+// description, tab separated, for shell completion (see git duet completion)
+func completeAuthors(dir cmd.Dir) {
+	configuration, err := (&duet.Loader{Dir: string(dir)}).Load(context.Background())
+	if err != nil {
+		os.Exit(1)
+	}
+
+	pairs, err := duet.NewPairsFromFile(configuration.PairsFile, "")
+	if err != nil {
+		os.Exit(1)
+	}
+
+	// the email lookup and LDAP directory aren't needed for names and could
+	// be slow
+	for _, candidate := range pairs.Candidates() {
+		fmt.Printf("%s\t%s\n", candidate.Initials, candidate.Name)
+	}
+
+	teams := pairs.Teams()
+	var names []string
+	for name := range teams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s\tteam: %s\n", name, strings.Join(teams[name], " "))
+	}
+}
+
+// defaultAuthorsFile returns the repository authors file
+func defaultAuthorsFile(dir cmd.Dir) string {
+	toplevel, err := duet.TopLevel(context.Background(), duet.ExecRunner{}, string(dir))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+	return path.Join(toplevel, ".git-authors")
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/internal/cmd"
+	"github.com/pborman/getopt"
+)
+
+const uncommitted = "0000000000000000000000000000000000000000"
+
+type line struct {
+	hash    string
+	number  string
+	content string
+}
+
+func main() {
+	var (
+		help      = getopt.BoolLong("help", 'h', "Help")
+		directory = cmd.DirOption(nil)
+	)
+
+	getopt.SetParameters("[<rev>] <file>")
+	getopt.Parse()
+	dir := string(*directory)
+
+	if *help {
+		getopt.Usage()
+		os.Exit(0)
+	}
+
+	if getopt.NArgs() < 1 || getopt.NArgs() > 2 {
+		getopt.Usage()
+		os.Exit(1)
+	}
+
+	configuration, err := (&duet.Loader{Dir: dir}).Load(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	pairs, err := configuration.Pairs()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	args := getopt.Args()
+	blameArgs := append([]string{"blame", "--line-porcelain"}, args[:len(args)-1]...)
+	lines, err := blame(dir, append(blameArgs, "--", args[len(args)-1])...)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	var hashes []string
+	seen := map[string]bool{}
+	for _, l := range lines {
+		if l.hash != uncommitted && !seen[l.hash] {
+			seen[l.hash] = true
+			hashes = append(hashes, l.hash)
+		}
+	}
+
+	credits := map[string]string{uncommitted: "Not Committed Yet"}
+	dates := map[string]string{uncommitted: ""}
+	if len(hashes) > 0 {
+		commits, err := duet.ReadHistory(context.Background(), duet.ExecRunner{}, dir, os.Stderr, append([]string{"--no-walk"}, hashes...)...)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+
+		for _, commit := range commits {
+			people, err := pairs.People(commit)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(duet.ExitCode(err))
+			}
+			credits[commit.Hash] = strings.Join(people, ",")
+			dates[commit.Hash] = commit.Time.Format("2006-01-02")
+		}
+	}
+
+	creditWidth, numberWidth := 0, 0
+	for _, l := range lines {
+		if len(credits[l.hash]) > creditWidth {
+			creditWidth = len(credits[l.hash])
+		}
+		if len(l.number) > numberWidth {
+			numberWidth = len(l.number)
+		}
+	}
+
+	for _, l := range lines {
+		fmt.Printf("%.8s (%-*s %10s %*s) %s\n",
+			l.hash, creditWidth, credits[l.hash], dates[l.hash], numberWidth, l.number, l.content)
+	}
+}
+
+// blame runs git blame in line porcelain mode in dir and returns the
+// originating commit of each line
+func blame(dir string, args ...string) (lines []*line, err error) {
+	output := new(bytes.Buffer)
+	err = duet.ExecRunner{}.Run(context.Background(), &duet.Command{
+		Name:   "git",
+		Args:   args,
+		Dir:    dir,
+		Stdout: output,
+		Stderr: os.Stderr,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var current *line
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		if strings.HasPrefix(text, "\t") {
+			current.content = text[1:]
+			lines = append(lines, current)
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			// header: <hash> <original line> <final line> [<lines in group>]
+			fields := strings.Fields(text)
+			current = &line{hash: fields[0], number: fields[2]}
+		}
+	}
+
+	return lines, scanner.Err()
+}
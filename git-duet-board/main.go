@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/internal/cmd"
+	"github.com/pborman/getopt"
+)
+
+const usage = `usage: git duet board <command> [<options>]
+
+commands:
+  apply     set the pairing from the board entry for the current branch or
+            this machine
+  set       put a pairing on the board (` + duet.BoardPath + `)
+`
+
+func main() {
+	set := getopt.New()
+	var (
+		help      = set.BoolLong("help", 'h', "Help")
+		directory = cmd.DirOption(set)
+	)
+
+	// options of the commands follow them
+	set.Parse(os.Args)
+	dir := *directory
+
+	if *help {
+		fmt.Print(usage)
+		os.Exit(0)
+	}
+
+	if set.NArgs() < 1 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	switch args := set.Args(); args[0] {
+	case "apply":
+		applyBoard(dir, args)
+	case "set":
+		setBoard(dir, args)
+	default:
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+}
+
+func applyBoard(dir cmd.Dir, args []string) {
+	set := getopt.New()
+	var (
+		quiet = set.BoolLong("quiet", 'q', "Silence output")
+		help  = set.BoolLong("help", 'h', "Help")
+	)
+
+	set.SetProgram("git duet board apply")
+	set.Parse(args)
+
+	if *help {
+		set.PrintUsage(os.Stdout)
+		os.Exit(0)
+	}
+
+	if set.NArgs() != 0 {
+		set.PrintUsage(os.Stdout)
+		os.Exit(1)
+	}
+
+	board, err := duet.ReadBoard(boardFile(dir))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+	branch, err := duet.CurrentBranch(context.Background(), duet.ExecRunner{}, string(dir))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	names, entry := board.Lookup(host, branch)
+	if len(names) == 0 {
+		if branch != "" {
+			fmt.Printf("nobody on the board for branch %s or host %s\n", branch, host)
+		} else {
+			fmt.Printf("nobody on the board for host %s\n", host)
+		}
+		os.Exit(1)
+	}
+
+	configuration, err := (&duet.Loader{Dir: string(dir)}).Load(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+	pairs, err := configuration.Pairs()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+	initials, err := pairs.Expand(names...)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	asArgs := initials
+	if *quiet {
+		asArgs = append([]string{"-q"}, initials...)
+	} else {
+		fmt.Printf("%s: %s\n", entry, strings.Join(initials, " "))
+	}
+
+	// git as sets either a solo or a pairing, and installs the hooks
+	err = duet.ExecRunner{}.Run(context.Background(), &duet.Command{
+		Name:   "git-as",
+		Args:   asArgs,
+		Dir:    string(dir),
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+}
+
+func setBoard(dir cmd.Dir, args []string) {
+	set := getopt.New()
+	var (
+		branch = set.StringLong("branch", 'b', "", "Branch to set the pairing for", "name")
+		host   = set.StringLong("host", 'H', "", "Machine to set the pairing for (this one by default)", "name")
+		help   = set.BoolLong("help", 'h', "Help")
+	)
+
+	set.SetProgram("git duet board set")
+	set.SetParameters("[<initials>...]")
+	set.Parse(args)
+
+	if *help {
+		set.PrintUsage(os.Stdout)
+		os.Exit(0)
+	}
+
+	if *branch != "" && *host != "" {
+		fmt.Println("--branch and --host are mutually exclusive")
+		os.Exit(1)
+	}
+
+	section, name := duet.BoardHosts, *host
+	if *branch != "" {
+		section, name = duet.BoardBranches, *branch
+	} else if name == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+		name = strings.SplitN(hostname, ".", 2)[0]
+	}
+
+	// check the initials, but keep teams as teams on the board
+	names := set.Args()
+	if len(names) > 0 {
+		configuration, err := (&duet.Loader{Dir: string(dir)}).Load(context.Background())
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+		pairs, err := configuration.Pairs()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+		if _, err = pairs.Expand(names...); err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+	}
+
+	file, err := duet.LoadBoardFile(boardFile(dir))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+	if err = file.Set(section, name, names...); err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+	if err = file.Save(); err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+}
+
+// boardFile returns the board file of the repository
+func boardFile(dir cmd.Dir) string {
+	toplevel, err := duet.TopLevel(context.Background(), duet.ExecRunner{}, string(dir))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+	return filepath.Join(toplevel, duet.BoardPath)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/git-duet/git-duet"
+	"github.com/pborman/getopt"
+)
+
+const usage = `usage: git duet cache <command> [<options>]
+
+commands:
+  clear     forget the cached email lookups
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "clear":
+		clearCache(os.Args[1:])
+	case "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+}
+
+func clearCache(args []string) {
+	set := getopt.New()
+	help := set.BoolLong("help", 'h', "Help")
+
+	set.SetProgram("git duet cache clear")
+	set.Parse(args)
+
+	if *help {
+		set.PrintUsage(os.Stdout)
+		os.Exit(0)
+	}
+
+	if set.NArgs() != 0 {
+		set.PrintUsage(os.Stdout)
+		os.Exit(1)
+	}
+
+	dir := duet.DefaultCacheDir(os.Getenv)
+	if dir == "" {
+		return
+	}
+	if err := duet.ClearCache(dir); err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+}
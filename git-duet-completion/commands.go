@@ -0,0 +1,196 @@
+package main
+
+import "strings"
+
+// command describes what is completed for a git-duet command: its options,
+// fixed words (subcommands or other keywords) and whether it takes initials
+type command struct {
+	name        string
+	flags       []flag
+	words       []string
+	initials    bool
+	wraps       string
+	subcommands []command
+}
+
+type flag struct {
+	short rune
+	long  string
+	help  string
+}
+
+var (
+	directory = flag{'C', "", "Run as if started in <path>"}
+	help      = flag{'h', "help", "Help"}
+	quiet     = flag{'q', "quiet", "Silence output"}
+	recurse   = flag{0, "recurse-submodules", "Include the initialized submodules"}
+	refresh   = flag{0, "refresh", "Run the email lookup again"}
+	global    = flag{'g', "global", "Change global config"}
+	show      = flag{'s', "show", "Show"}
+	version   = flag{'v', "version", "Version"}
+	workspace = flag{'w', "workspace", "Workspace directory or manifest file"}
+)
+
+// commands are the binaries completed, named the way git runs them (`duet`
+// for `git duet` and `git-duet`)
+var commands = []command{
+	{
+		name: "duet",
+		flags: []flag{
+			directory, global, help,
+			{'i', "pick", "Pick the pairing interactively"},
+			{0, "install-hooks", "Also install the hooks"},
+			quiet, recurse, refresh, show, version, workspace,
+		},
+		initials: true,
+		subcommands: []command{
+			{
+				name:  "authors",
+				flags: []flag{directory, help},
+				subcommands: []command{
+					{
+						name: "add",
+						flags: []flag{
+							{'e', "email", "Email address (listed under email_addresses)"},
+							help,
+							{'u', "username", "Username used to build the email address"},
+						},
+					},
+					{name: "remove", flags: []flag{help}, initials: true},
+					{name: "rename", flags: []flag{help}, initials: true},
+					{
+						name: "import",
+						flags: []flag{
+							{'f', "force", "Overwrite an existing authors file"},
+							{0, "from-mailmap", "Import the proper identities from a .mailmap file instead of git log"},
+							help,
+							{'o', "output", "Authors file to write"},
+							{'y', "yes", "Accept the proposed initials without reviewing them"},
+						},
+					},
+				},
+			},
+			{name: "blame", flags: []flag{directory, help}},
+			{
+				name:  "board",
+				flags: []flag{directory, help},
+				subcommands: []command{
+					{name: "apply", flags: []flag{help, quiet}},
+					{
+						name: "set",
+						flags: []flag{
+							{'b', "branch", "Branch to set the pairing for"},
+							help,
+							{'H', "host", "Machine to set the pairing for"},
+						},
+						initials: true,
+					},
+				},
+			},
+			{
+				name:        "cache",
+				flags:       []flag{help},
+				subcommands: []command{{name: "clear", flags: []flag{help}}},
+			},
+			{name: "completion", flags: []flag{help}, words: shells},
+			{
+				name: "config",
+				flags: []flag{
+					directory, help,
+					{'l', "list", "List the effective settings and where they come from"},
+				},
+			},
+			{
+				name: "mailmap",
+				flags: []flag{
+					directory, help,
+					{'o', "output", "Mailmap file to update"},
+					quiet,
+					{'r', "report", "Write identities missing from the authors file to this file"},
+				},
+			},
+			{
+				name: "shortlog",
+				flags: []flag{
+					directory,
+					{'e', "email", "Show email addresses"},
+					help,
+					{'n', "numbered", "Sort by number of commits instead of name"},
+					{'s', "summary", "Only show the number of commits per person"},
+				},
+			},
+			{
+				name: "stats",
+				flags: []flag{
+					directory,
+					{'a', "all", "Include pairs from the authors file that never paired"},
+					{'f', "format", "Output format (table, csv or json)"},
+					help,
+					{0, "since", "Only count commits more recent than a date"},
+					{0, "until", "Only count commits older than a date"},
+				},
+			},
+			{
+				name:     "status",
+				flags:    []flag{directory, help, workspace},
+				initials: true,
+			},
+			{
+				name: "suggest",
+				flags: []flag{
+					directory, help,
+					{'n', "never", "Pairs that must not pair"},
+					{0, "seniors", "Initials of seniors"},
+					{0, "since", "Only consider pairings more recent than a date"},
+				},
+				initials: true,
+			},
+		},
+	},
+	{name: "solo", flags: []flag{directory, global, help, quiet, recurse, refresh, show, version}, initials: true},
+	{name: "as", flags: []flag{directory, global, help, quiet, recurse, refresh, show, version}, initials: true},
+	{
+		name:  "duet-install-hook",
+		flags: []flag{directory, help, quiet, recurse},
+		words: []string{"pre-commit", "prepare-commit-msg", "post-commit"},
+	},
+	{name: "duet-commit", wraps: "commit"},
+	{name: "duet-merge", wraps: "merge"},
+	{name: "duet-revert", wraps: "revert"},
+}
+
+// walk calls fn for the command and its subcommands, with the path of names
+// leading to each
+func (c command) walk(path []string, fn func(path []string, c command)) {
+	path = append(append([]string{}, path...), c.name)
+	fn(path, c)
+	for _, s := range c.subcommands {
+		s.walk(path, fn)
+	}
+}
+
+// options returns the options as typed on the command line
+func (c command) options() (options []string) {
+	for _, f := range c.flags {
+		if f.long != "" {
+			options = append(options, "--"+f.long)
+		}
+		if f.short != 0 {
+			options = append(options, "-"+string(f.short))
+		}
+	}
+	return options
+}
+
+// candidates returns the subcommands and fixed words
+func (c command) candidates() (candidates []string) {
+	for _, s := range c.subcommands {
+		candidates = append(candidates, s.name)
+	}
+	return append(candidates, c.words...)
+}
+
+// functionName returns a shell function name for the command path
+func functionName(prefix string, path []string) string {
+	return prefix + strings.Replace(strings.Join(path, "_"), "-", "_", -1)
+}
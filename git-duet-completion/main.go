@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pborman/getopt"
+)
+
+var shells = []string{"bash", "zsh", "fish"}
+
+var generators = map[string]func(w io.Writer){
+	"bash": writeBash,
+	"zsh":  writeZsh,
+	"fish": writeFish,
+}
+
+func main() {
+	help := getopt.BoolLong("help", 'h', "Help")
+
+	getopt.SetProgram("git duet completion")
+	getopt.SetParameters(fmt.Sprintf("{ %s }", strings.Join(shells, " | ")))
+	getopt.Parse()
+
+	if *help {
+		getopt.Usage()
+		os.Exit(0)
+	}
+
+	if getopt.NArgs() != 1 {
+		getopt.Usage()
+		os.Exit(1)
+	}
+
+	generate, ok := generators[getopt.Arg(0)]
+	if !ok {
+		fmt.Printf("unknown shell %s (expected %s)\n", getopt.Arg(0), strings.Join(shells, ", "))
+		os.Exit(1)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	generate(out)
+	if err := out.Flush(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// subcommandPaths returns every command path below a top level command, as
+// "duet authors", for deciding what is being completed
+func subcommandPaths() (paths []string) {
+	for _, c := range commands {
+		c.walk(nil, func(path []string, _ command) {
+			if len(path) > 1 {
+				paths = append(paths, strings.Join(path, " "))
+			}
+		})
+	}
+	return paths
+}
+
+func quoteAll(words []string) string {
+	var quoted []string
+	for _, w := range words {
+		quoted = append(quoted, `"`+w+`"`)
+	}
+	return strings.Join(quoted, "|")
+}
+
+func writeBash(w io.Writer) {
+	fmt.Fprint(w, `# bash completion for git-duet
+# load it with: source <(git duet completion bash)
+
+__git_duet_initials() {
+	git duet authors --complete 2>/dev/null | cut -f1
+}
+
+# __git_duet_path sets path to the command being completed, e.g. "duet
+# authors", and positional if it already has arguments
+__git_duet_path() {
+	local i=0
+	if [[ "${COMP_WORDS[0]##*/}" == git ]]; then
+		i=${__git_cmd_idx:-1}
+	fi
+	path="${COMP_WORDS[i]##*/}"
+	path="${path#git-}"
+
+	for ((i++; i < COMP_CWORD; i++)); do
+		case "${COMP_WORDS[i]}" in
+		-C)
+			((i++))
+			continue
+			;;
+		-*) continue ;;
+		esac
+		case "$path ${COMP_WORDS[i]}" in
+`)
+	fmt.Fprintf(w, "\t\t%s) path=\"$path ${COMP_WORDS[i]}\" ;;\n", quoteAll(subcommandPaths()))
+	fmt.Fprint(w, `		*)
+			positional=1
+			break
+			;;
+		esac
+	done
+}
+
+__git_duet() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" path positional flags words initials
+
+	if [[ "${COMP_WORDS[COMP_CWORD-1]}" == -C ]]; then
+		COMPREPLY=($(compgen -d -- "$cur"))
+		return
+	fi
+
+	__git_duet_path
+	case "$path" in
+`)
+	for _, top := range commands {
+		top.walk(nil, func(path []string, c command) {
+			fmt.Fprintf(w, "\t\"%s\")\n", strings.Join(path, " "))
+			if c.wraps != "" {
+				fmt.Fprintf(w, "\t\tif [[ \"${COMP_WORDS[0]##*/}\" == git ]]; then\n")
+				fmt.Fprintf(w, "\t\t\tdeclare -F _git_%s >/dev/null && _git_%s\n", c.wraps, c.wraps)
+				fmt.Fprintf(w, "\t\telse\n")
+				fmt.Fprintf(w, "\t\t\tdeclare -F __git_func_wrap >/dev/null && __git_func_wrap _git_%s\n", c.wraps)
+				fmt.Fprintf(w, "\t\tfi\n")
+				fmt.Fprintf(w, "\t\treturn\n")
+				fmt.Fprintf(w, "\t\t;;\n")
+				return
+			}
+			fmt.Fprintf(w, "\t\tflags=\"%s\"\n", strings.Join(c.options(), " "))
+			fmt.Fprintf(w, "\t\twords=\"%s\"\n", strings.Join(c.candidates(), " "))
+			if c.initials {
+				fmt.Fprintf(w, "\t\tinitials=1\n")
+			}
+			fmt.Fprintf(w, "\t\t;;\n")
+		})
+	}
+	fmt.Fprint(w, `	esac
+
+	if [[ -n "$positional" ]]; then
+		words=""
+	fi
+
+	if [[ "$cur" == -* ]]; then
+		COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+	else
+		if [[ -n "$initials" ]]; then
+			words="$words $(__git_duet_initials)"
+		fi
+		COMPREPLY=($(compgen -W "$words" -- "$cur"))
+	fi
+}
+
+`)
+	var binaries []string
+	for _, c := range commands {
+		fmt.Fprintf(w, "%s() { __git_duet; }\n", functionName("_git_", []string{c.name}))
+		binaries = append(binaries, "git-"+c.name)
+	}
+	fmt.Fprintf(w, "\ncomplete -o default -F __git_duet %s\n", strings.Join(binaries, " "))
+}
+
+func writeZsh(w io.Writer) {
+	var binaries []string
+	for _, c := range commands {
+		binaries = append(binaries, "git-"+c.name)
+	}
+
+	fmt.Fprintf(w, "#compdef %s\n", strings.Join(binaries, " "))
+	fmt.Fprint(w, `# zsh completion for git-duet
+# load it with: source <(git duet completion zsh)
+
+# __git_duet_path sets path to the command being completed, e.g. "duet
+# authors", and positional if it already has arguments
+__git_duet_path() {
+	local i
+	path=${words[1]:t}
+	path=${path#git-}
+
+	for (( i = 2; i < CURRENT; i++ )); do
+		if [[ $words[i] == -C ]]; then
+			(( i++ ))
+			continue
+		fi
+		[[ $words[i] == -* ]] && continue
+		case "$path $words[i]" in
+`)
+	fmt.Fprintf(w, "\t\t(%s) path=\"$path $words[i]\" ;;\n", quoteAll(subcommandPaths()))
+	fmt.Fprint(w, `		(*)
+			positional=1
+			break
+			;;
+		esac
+	done
+}
+
+_git-duet() {
+	local -a flags candidates authors
+	local path positional initials
+
+	if [[ $words[CURRENT-1] == -C ]]; then
+		_directories
+		return
+	fi
+
+	__git_duet_path
+	case "$path" in
+`)
+	for _, top := range commands {
+		top.walk(nil, func(path []string, c command) {
+			fmt.Fprintf(w, "\t(\"%s\")\n", strings.Join(path, " "))
+			if c.wraps != "" {
+				fmt.Fprintf(w, "\t\twords=(git %s \"${(@)words[2,-1]}\")\n", c.wraps)
+				fmt.Fprintf(w, "\t\t(( CURRENT++ ))\n")
+				fmt.Fprintf(w, "\t\t_git\n")
+				fmt.Fprintf(w, "\t\treturn\n")
+				fmt.Fprintf(w, "\t\t;;\n")
+				return
+			}
+			var flags []string
+			for _, f := range c.flags {
+				if f.long != "" {
+					flags = append(flags, fmt.Sprintf("'--%s:%s'", f.long, f.help))
+				}
+				if f.short != 0 {
+					flags = append(flags, fmt.Sprintf("'-%c:%s'", f.short, f.help))
+				}
+			}
+			fmt.Fprintf(w, "\t\tflags=(%s)\n", strings.Join(flags, " "))
+			fmt.Fprintf(w, "\t\tcandidates=(%s)\n", strings.Join(c.candidates(), " "))
+			if c.initials {
+				fmt.Fprintf(w, "\t\tinitials=1\n")
+			}
+			fmt.Fprintf(w, "\t\t;;\n")
+		})
+	}
+	fmt.Fprint(w, `	esac
+
+	if [[ $PREFIX == -* ]]; then
+		_describe -t options option flags
+		return
+	fi
+
+	[[ -n $positional ]] && candidates=()
+	(( $#candidates )) && _describe -t commands command candidates
+	if [[ -n $initials ]]; then
+		authors=(${(f)"$(git duet authors --complete 2>/dev/null)"})
+		authors=(${authors//$'\t'/:})
+		_describe -t authors author authors
+	fi
+}
+
+`)
+	for _, c := range commands[1:] {
+		fmt.Fprintf(w, "_git-%s() { _git-duet \"$@\"; }\n", c.name)
+	}
+	fmt.Fprintf(w, "\ncompdef _git-duet %s\n", strings.Join(binaries, " "))
+}
+
+func writeFish(w io.Writer) {
+	fmt.Fprint(w, `# fish completion for git-duet
+# load it with: git duet completion fish | source
+
+# __git_duet_path prints the command being completed, e.g. "duet authors",
+# followed by "args" if it already has arguments
+function __git_duet_path
+	set -l tokens (commandline -opc)
+	if test (basename -- $tokens[1]) = git
+		set -e tokens[1]
+		while string match -q -- '-*' $tokens[1]
+			# options of git taking an argument
+			if contains -- $tokens[1] -C -c
+				set -e tokens[1]
+			end
+			set -e tokens[1]
+		end
+	end
+	test (count $tokens) -gt 0; or return
+
+	set -l path (string replace -r '^(.*/)?git-' '' -- $tokens[1])
+	set -e tokens[1]
+	set -l skip
+	for token in $tokens
+		if set -q skip[1]
+			set -e skip
+			continue
+		end
+		if test "$token" = -C
+			set skip 1
+			continue
+		end
+		string match -q -- '-*' $token; and continue
+		switch "$path $token"
+`)
+	var cases []string
+	for _, p := range subcommandPaths() {
+		cases = append(cases, "'"+p+"'")
+	}
+	fmt.Fprintf(w, "\t\t\tcase %s\n", strings.Join(cases, " "))
+	fmt.Fprint(w, `				set path "$path $token"
+			case '*'
+				echo $path
+				echo args
+				return
+		end
+	end
+	echo $path
+end
+
+function __git_duet_at
+	set -l path (__git_duet_path)
+	test "$path[1]" = "$argv"
+end
+
+function __git_duet_first_at
+	set -l path (__git_duet_path)
+	test (count $path) -eq 1 -a "$path[1]" = "$argv"
+end
+`)
+	for _, top := range commands {
+		fmt.Fprintln(w)
+		if top.wraps != "" {
+			fmt.Fprintf(w, "complete -c git-%s -w 'git %s'\n", top.name, top.wraps)
+			continue
+		}
+		top.walk(nil, func(path []string, c command) {
+			condition := fmt.Sprintf("-n '__git_duet_at %s'", strings.Join(path, " "))
+			// file names only make sense for commands without other arguments
+			if len(c.candidates()) > 0 || c.initials {
+				condition = "-f " + condition
+			}
+			for _, program := range []string{"git", "git-" + top.name} {
+				for _, f := range c.flags {
+					var names string
+					if f.short != 0 {
+						names += fmt.Sprintf(" -s %c", f.short)
+					}
+					if f.long != "" {
+						names += fmt.Sprintf(" -l %s", f.long)
+					}
+					if f == directory {
+						names += " -x -a '(__fish_complete_directories)'"
+					}
+					fmt.Fprintf(w, "complete -c %s %s%s -d '%s'\n", program, condition, names, f.help)
+				}
+				if candidates := c.candidates(); len(candidates) > 0 {
+					first := strings.Replace(condition, "__git_duet_at", "__git_duet_first_at", 1)
+					fmt.Fprintf(w, "complete -c %s %s -a '%s'\n", program, first, strings.Join(candidates, " "))
+				}
+				if c.initials {
+					fmt.Fprintf(w, "complete -c %s %s -a '(git duet authors --complete 2>/dev/null)'\n", program, condition)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/internal/cmd"
+	"github.com/pborman/getopt"
+)
+
+func main() {
+	var (
+		list      = getopt.BoolLong("list", 'l', "List the effective settings and where they come from")
+		help      = getopt.BoolLong("help", 'h', "Help")
+		directory = cmd.DirOption(nil)
+	)
+
+	getopt.SetProgram("git duet config")
+	getopt.Parse()
+
+	if *help {
+		getopt.Usage()
+		os.Exit(0)
+	}
+
+	if !*list || getopt.NArgs() != 0 {
+		getopt.Usage()
+		os.Exit(1)
+	}
+
+	settings, err := (&duet.Loader{Dir: string(*directory)}).Settings(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	for _, setting := range settings {
+		fmt.Printf("%s\t%s=%s\n", setting.Source, setting.Key, setting.Value)
+	}
+}
@@ -1,16 +1,16 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"os/user"
 	"path"
 	"strings"
 
 	duet "github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/internal/cmd"
 	"github.com/pborman/getopt"
 )
 
@@ -24,11 +24,14 @@ const postCommitHook = `exec git duet-post-commit "$@"`
 
 func main() {
 	var (
-		quiet = getopt.BoolLong("quiet", 'q', "Silence output")
-		help  = getopt.BoolLong("help", 'h', "Help")
+		quiet     = getopt.BoolLong("quiet", 'q', "Silence output")
+		help      = getopt.BoolLong("help", 'h', "Help")
+		recurse   = getopt.BoolLong("recurse-submodules", 0, "Also install the hook in the initialized submodules")
+		directory = cmd.DirOption(nil)
 	)
 
 	getopt.Parse()
+	dir := string(*directory)
 	getopt.SetParameters(fmt.Sprintf("{ %s | %s | %s }", preCommit, prepareCommitMsg, postCommit))
 
 	if *help {
@@ -55,91 +58,122 @@ func main() {
 		os.Exit(1)
 	}
 
-	config, err := duet.NewConfiguration()
+	config, err := (&duet.Loader{Dir: dir}).Load(context.Background())
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
 
 	var hooksDir string
 	if config.Global {
-		gitConfig := &duet.GitConfig{Namespace: config.Namespace, SetUserConfig: config.SetGitUserConfig}
+		gitConfig := &duet.GitConfig{Namespace: config.Namespace, SetUserConfig: config.SetGitUserConfig, Dir: dir}
 		gitConfig.Scope = duet.Global
 		templateDir, err := gitConfig.GetInitTemplateDir()
 		if err != nil {
 			fmt.Println(err)
-			os.Exit(1)
+			os.Exit(duet.ExitCode(err))
 		}
 		if templateDir == "" {
 			usr, err := user.Current()
 			if err != nil {
 				fmt.Println(err)
-				os.Exit(1)
+				os.Exit(duet.ExitCode(err))
 			}
 			templateDir = path.Join(usr.HomeDir, ".git-template")
 			if err := gitConfig.SetInitTemplateDir(templateDir); err != nil {
 				fmt.Println(err)
-				os.Exit(1)
+				os.Exit(duet.ExitCode(err))
 			}
 		}
 		if err := os.MkdirAll(path.Join(templateDir, "hooks"), os.ModePerm); err != nil {
 			fmt.Println(err)
-			os.Exit(1)
+			os.Exit(duet.ExitCode(err))
 		}
 		hooksDir = path.Join(templateDir, "hooks")
 	} else {
-		hooksDir = getLocalHooksDir()
+		hooksDir = getLocalHooksDir(dir)
+	}
+
+	code := installHook(hooksDir, hookFileName, hook, *quiet)
+	if *recurse {
+		submodules, err := duet.Submodules(context.Background(), duet.ExecRunner{}, dir)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+		for _, submodule := range submodules {
+			hooksDir, err := duet.HooksDir(context.Background(), duet.ExecRunner{}, submodule.Dir)
+			if err == nil {
+				err = os.MkdirAll(hooksDir, os.ModePerm)
+			}
+			if err != nil {
+				fmt.Printf("%s: %s\n", submodule.Path, err)
+				if code == 0 {
+					code = duet.ExitCode(err)
+				}
+				continue
+			}
+			if c := installHook(hooksDir, hookFileName, hook, *quiet); code == 0 {
+				code = c
+			}
+		}
 	}
+	os.Exit(code)
+}
 
+// installHook writes the hook to hooksDir unless it's already there, and
+// returns the exit code
+func installHook(hooksDir, hookFileName, hook string, quiet bool) int {
 	hookPath := path.Join(hooksDir, hookFileName)
 
 	hookFile, err := os.OpenFile(hookPath, os.O_CREATE|os.O_RDWR, os.ModePerm)
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		return duet.ExitCode(err)
 	}
 	defer hookFile.Close()
 
 	b, err := ioutil.ReadAll(hookFile)
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		return duet.ExitCode(err)
 	}
 
 	contents := strings.TrimSpace(string(b))
 	if contents != "" {
-		if hook == preCommitHook && !strings.Contains(contents, preCommitHook) ||
-			hook == prepareCommitMsgHook && !strings.Contains(contents, prepareCommitMsgHook) ||
-			hook == postCommitHook && !strings.Contains(contents, postCommitHook) {
+		if !strings.Contains(contents, hook) {
 			fmt.Printf(`It seems you already have a "%s" hook.
 To enable the git-duet hook, please append:
 
   %s
 
-to your %s file.`, hookFileName, hook, hookPath)
-			os.Exit(1)
+to your %s file.
+`, hookFileName, hook, hookPath)
+			return 1
 		}
-		os.Exit(0) // hook file with the desired content already exists
+		return 0 // hook file with the desired content already exists
 	}
 
 	if _, err = hookFile.WriteString(sheBangBash + hook); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		return duet.ExitCode(err)
 	}
 
-	if !*quiet {
+	if !quiet {
 		fmt.Printf("git-duet-install-hook: Installed hook to %s\n", hookPath)
 	}
-
+	return 0
 }
 
-func getLocalHooksDir() string {
-	output := new(bytes.Buffer)
-	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-	cmd.Stdout = output
-	if err := cmd.Run(); err != nil {
+func getLocalHooksDir(dir string) string {
+	hooksDir, err := duet.HooksDir(context.Background(), duet.ExecRunner{}, dir)
+	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
+	}
+	if err := os.MkdirAll(hooksDir, os.ModePerm); err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
 	}
-	return path.Join(strings.TrimSpace(output.String()), ".git", "hooks")
+	return hooksDir
 }
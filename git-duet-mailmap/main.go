@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/internal/cmd"
+	"github.com/pborman/getopt"
+)
+
+func main() {
+	var (
+		output    = getopt.StringLong("output", 'o', "", "Mailmap file to update (- for standard output, defaults to .mailmap in the repository)")
+		report    = getopt.StringLong("report", 'r', "", "Write identities missing from the authors file to this file instead of standard error")
+		quiet     = getopt.BoolLong("quiet", 'q', "Silence output")
+		help      = getopt.BoolLong("help", 'h', "Help")
+		directory = cmd.DirOption(nil)
+	)
+
+	getopt.Parse()
+	dir := string(*directory)
+
+	if *help {
+		getopt.Usage()
+		os.Exit(0)
+	}
+
+	configuration, err := (&duet.Loader{Dir: dir}).Load(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	pairs, err := configuration.Pairs()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	if *output == "" {
+		toplevel, err := duet.TopLevel(context.Background(), duet.ExecRunner{}, dir)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+		*output = path.Join(toplevel, ".mailmap")
+	} else if *output != "-" {
+		*output = directory.Path(*output)
+	}
+
+	var existing []byte
+	if *output != "-" {
+		if existing, err = ioutil.ReadFile(*output); err != nil && !os.IsNotExist(err) {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+	}
+
+	mapped, err := duet.ParseMailmap(bytes.NewReader(existing))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	commits, err := duet.ReadHistory(context.Background(), duet.ExecRunner{}, dir, os.Stderr, "--all")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	var entries []duet.MailmapEntry
+	var unknown []duet.Identity
+	seen := map[duet.Identity]bool{}
+	for _, commit := range commits {
+		for _, identity := range commit.Participants() {
+			if seen[identity] {
+				continue
+			}
+			seen[identity] = true
+
+			pair, err := pairs.Identify(identity)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(duet.ExitCode(err))
+			}
+
+			if pair == nil {
+				if !isMapped(mapped, identity) {
+					unknown = append(unknown, identity)
+				}
+				continue
+			}
+
+			proper := duet.Identity{Name: pair.Name, Email: pair.Email}
+			if identity != proper && !isMapped(mapped, identity) {
+				entries = append(entries, duet.MailmapEntry{Proper: proper, Commit: identity})
+			}
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].String() < entries[j].String() })
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].String() < unknown[j].String() })
+
+	var lines bytes.Buffer
+	lines.Write(existing)
+	if len(existing) > 0 && !bytes.HasSuffix(existing, []byte("\n")) {
+		lines.WriteString("\n")
+	}
+	for _, entry := range entries {
+		lines.WriteString(entry.String() + "\n")
+	}
+
+	if *output == "-" {
+		os.Stdout.Write(lines.Bytes())
+	} else if len(entries) > 0 {
+		if err = ioutil.WriteFile(*output, lines.Bytes(), 0644); err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+	}
+
+	if !*quiet && *output != "-" {
+		fmt.Printf("git-duet-mailmap: added %d entries to %s\n", len(entries), *output)
+	}
+
+	if len(unknown) > 0 {
+		if err = writeReport(directory.Path(*report), unknown); err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+	}
+}
+
+// isMapped returns whether an entry of the mailmap already applies to the
+// identity
+func isMapped(mapped []duet.MailmapEntry, identity duet.Identity) bool {
+	for _, entry := range mapped {
+		if strings.EqualFold(entry.Commit.Email, identity.Email) &&
+			(entry.Commit.Name == "" || entry.Commit.Name == identity.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeReport(report string, unknown []duet.Identity) (err error) {
+	var w io.Writer = os.Stderr
+	if report != "" {
+		f, err := os.Create(report)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	fmt.Fprintln(w, "identities not in the authors file (review and add them to the authors file or .mailmap):")
+	for _, identity := range unknown {
+		fmt.Fprintf(w, "  %s\n", identity)
+	}
+	return nil
+}
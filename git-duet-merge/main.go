@@ -1,12 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 
+	"github.com/git-duet/git-duet"
 	"github.com/git-duet/git-duet/internal/cmd"
 	"github.com/git-duet/git-duet/internal/cmdrunner"
 )
@@ -15,17 +17,17 @@ func main() {
 	err := cmd.New("merge").Execute()
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
 
-	output, err := exec.Command("git", "rev-list", "--merges", "HEAD~1..HEAD").Output()
+	output, err := revList("--merges", "HEAD~1..HEAD")
 	if err != nil { // if error, check if it was because there was only one or zero commits in the repo
-		output, err = exec.Command("git", "rev-list", "--count", "HEAD").Output()
+		output, err = revList("--count", "HEAD")
 		if err != nil {
 			fmt.Printf("error checking if HEAD has more than 1 commit: %s\n", err)
 			os.Exit(1)
 		}
-		numCommits, err := strconv.Atoi(strings.TrimSpace(string(output)))
+		numCommits, err := strconv.Atoi(strings.TrimSpace(output))
 		if err != nil {
 			fmt.Printf("error checking if HEAD has more than 1 commit: %s\n", err)
 			os.Exit(1)
@@ -48,6 +50,17 @@ func main() {
 	)
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
 }
+
+// revList runs git rev-list with the given arguments and returns its output
+func revList(args ...string) (string, error) {
+	output := new(bytes.Buffer)
+	err := duet.ExecRunner{}.Run(context.Background(), &duet.Command{
+		Name:   "git",
+		Args:   append([]string{"rev-list"}, args...),
+		Stdout: output,
+	})
+	return output.String(), err
+}
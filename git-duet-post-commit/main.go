@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/git-duet/git-duet"
 	"github.com/git-duet/git-duet/internal/cmdrunner"
 )
 
@@ -11,6 +12,6 @@ func main() {
 	err := cmdrunner.Execute() // rotates authors
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
 }
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/git-duet/git-duet"
@@ -12,22 +14,79 @@ func main() {
 	configuration, err := duet.NewConfiguration()
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
 
-	gitConfig := &duet.GitConfig{
-		Namespace: configuration.Namespace,
+	var gitConfig *duet.GitConfig
+	if configuration.Global {
+		gitConfig = &duet.GitConfig{
+			Namespace:     configuration.Namespace,
+			Scope:         duet.Global,
+			SetUserConfig: configuration.SetGitUserConfig,
+		}
+	} else {
+		// check staleness against whichever config the authorship is drawn from
+		gitConfig, err = duet.GetAuthorConfig(configuration.Namespace, configuration.SetGitUserConfig)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
 	}
 
-	mtime, err := gitConfig.GetMtime()
+	stale, err := configuration.IsStale(gitConfig, time.Now())
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
 
-	if mtime.Add(configuration.StaleCutoff).Before(time.Now()) {
-		fmt.Println("your git duet settings are stale")
+	if !stale {
+		os.Exit(0)
+	}
+
+	switch configuration.StalePolicy {
+	case duet.StaleWarn:
+		fmt.Println("warning: your git duet settings are stale")
 		fmt.Println("update them with `git duet` or `git solo`.")
-		os.Exit(1)
+		os.Exit(0)
+	case duet.StalePrompt:
+		if confirmed("your git duet settings are stale.\nkeep them? [y/N] ") {
+			if err = gitConfig.Touch(); err != nil {
+				fmt.Println(err)
+				os.Exit(duet.ExitCode(err))
+			}
+			os.Exit(0)
+		}
+	case duet.StaleSolo:
+		author, err := gitConfig.RevertToSolo()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+		fmt.Println("your git duet settings are stale")
+		fmt.Printf("reverted to solo author %s <%s>, commit again to use them.\n", author.Name, author.Email)
+		os.Exit(duet.ExitCode(duet.ErrStale))
 	}
+
+	fmt.Println("your git duet settings are stale")
+	fmt.Println("update them with `git duet` or `git solo`.")
+	os.Exit(duet.ExitCode(duet.ErrStale))
+}
+
+// confirmed asks the question on the controlling terminal since git does not
+// connect hooks to stdin, returns false if there is no terminal
+func confirmed(question string) bool {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, question)
+	answer, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
 }
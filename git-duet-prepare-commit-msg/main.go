@@ -1,11 +1,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"os/exec"
-	"regexp"
 
 	"github.com/git-duet/git-duet"
 	"github.com/pborman/getopt"
@@ -23,7 +21,7 @@ func main() {
 	configuration, err := duet.NewConfiguration()
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
 
 	var gitConfig *duet.GitConfig
@@ -37,59 +35,18 @@ func main() {
 		gitConfig, err = duet.GetAuthorConfig(configuration.Namespace, configuration.SetGitUserConfig)
 		if err != nil {
 			fmt.Println(err)
-			os.Exit(1)
+			os.Exit(duet.ExitCode(err))
 		}
 	}
 
 	committers, err := gitConfig.GetCommitters()
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
-	if committers == nil || len(committers) == 0 {
-		os.Exit(0)
-	}
-
-	commitMsg, err := ioutil.ReadFile(commitMsgFile)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-
-	coAuthorTrailerRegexp := regexp.MustCompile(`Co-authored-by:\s.+\s<.+>`)
-	trailerExists := coAuthorTrailerRegexp.Match(commitMsg)
-	if trailerExists && commitMsgSource != "commit" {
-		/* The goal here is to not add trailers in interactive rebasing or cherry-picking
-		   since authorship doesn't get changed. Since this hook doesn't know whether it is invoked
-		   as part of rebasing or cherry-picking, at the very least, it checks for existing trailers,
-		   and if there is one, no new trailers will be appended.
-		   Trailers will still be appended for "git commit --amend" in which case the
-		   commitMsgSource's value is "commit". */
-		os.Exit(0)
-	}
-
-	for _, c := range committers {
-		trailer := "Co-authored-by: " + c.Name + " <" + c.Email + ">"
-		cmd := exec.Command("git", "interpret-trailers", "--in-place", "--trailer", trailer, commitMsgFile)
-		err := cmd.Run()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-	}
-
-	// prepend an empty line to the trailers block if there aren't trailers yet
-	if trailerExists || commitMsgSource == "commit" {
-		os.Exit(0)
-	}
-	commitMsg, err = ioutil.ReadFile(commitMsgFile)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-	err = ioutil.WriteFile(commitMsgFile, []byte(fmt.Sprintf("\n%s", string(commitMsg))), 0644)
+	err = duet.AddCoAuthoredBy(context.Background(), duet.ExecRunner{}, commitMsgFile, commitMsgSource, committers)
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
 }
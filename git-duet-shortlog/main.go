@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/internal/cmd"
+	"github.com/pborman/getopt"
+)
+
+type contributor struct {
+	participant duet.Participant
+	subjects    []string
+}
+
+func main() {
+	var (
+		summary   = getopt.BoolLong("summary", 's', "Only show the number of commits per person")
+		numbered  = getopt.BoolLong("numbered", 'n', "Sort by number of commits instead of name")
+		email     = getopt.BoolLong("email", 'e', "Show email addresses")
+		help      = getopt.BoolLong("help", 'h', "Help")
+		directory = cmd.DirOption(nil)
+	)
+
+	getopt.SetParameters("[<revision range>] [<path>...]")
+	getopt.Parse()
+	dir := string(*directory)
+
+	if *help {
+		getopt.Usage()
+		os.Exit(0)
+	}
+
+	configuration, err := (&duet.Loader{Dir: dir}).Load(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	pairs, err := configuration.Pairs()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	commits, err := duet.ReadHistory(context.Background(), duet.ExecRunner{}, dir, os.Stderr, getopt.Args()...)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+	cmd.WarnInvalidNotes(os.Stderr, commits)
+
+	byPerson := map[string]*contributor{}
+	var contributors []*contributor
+	for _, commit := range commits {
+		participants, err := pairs.Participants(commit)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+
+		for _, participant := range participants {
+			c, ok := byPerson[participant.Person()]
+			if !ok {
+				c = &contributor{participant: participant}
+				byPerson[participant.Person()] = c
+				contributors = append(contributors, c)
+			}
+			c.subjects = append(c.subjects, commit.Subject)
+		}
+	}
+
+	sort.SliceStable(contributors, func(i, j int) bool {
+		if *numbered && len(contributors[i].subjects) != len(contributors[j].subjects) {
+			return len(contributors[i].subjects) > len(contributors[j].subjects)
+		}
+		return strings.ToLower(contributors[i].participant.Name) < strings.ToLower(contributors[j].participant.Name)
+	})
+
+	for _, c := range contributors {
+		name := c.participant.Name
+		if *email {
+			name = c.participant.String()
+		}
+
+		if *summary {
+			fmt.Printf("%6d\t%s\n", len(c.subjects), name)
+			continue
+		}
+
+		fmt.Printf("%s (%d):\n", name, len(c.subjects))
+		// like git shortlog, list the oldest commit first
+		for i := len(c.subjects) - 1; i >= 0; i-- {
+			fmt.Printf("      %s\n", c.subjects[i])
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/internal/cmd"
+	"github.com/pborman/getopt"
+)
+
+const dateFormat = "2006-01-02"
+
+type jsonPairing struct {
+	Pair       [2]string  `json:"pair"`
+	Commits    int        `json:"commits"`
+	LastPaired *time.Time `json:"last_paired"`
+}
+
+func main() {
+	var (
+		format    = getopt.EnumLong("format", 'f', []string{"table", "csv", "json"}, "table", "Output format (table, csv or json)")
+		since     = getopt.StringLong("since", 0, "", "Only count commits more recent than a date")
+		until     = getopt.StringLong("until", 0, "", "Only count commits older than a date")
+		all       = getopt.BoolLong("all", 'a', "Include pairs from the authors file that never paired")
+		help      = getopt.BoolLong("help", 'h', "Help")
+		directory = cmd.DirOption(nil)
+	)
+
+	getopt.SetParameters("[-- <path>...]")
+	getopt.Parse()
+	dir := string(*directory)
+
+	if *help {
+		getopt.Usage()
+		os.Exit(0)
+	}
+
+	configuration, err := (&duet.Loader{Dir: dir}).Load(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	pairs, err := configuration.Pairs()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	var args []string
+	if *since != "" {
+		args = append(args, "--since="+*since)
+	}
+	if *until != "" {
+		args = append(args, "--until="+*until)
+	}
+	args = append(append(args, "--"), getopt.Args()...)
+
+	commits, err := duet.ReadHistory(context.Background(), duet.ExecRunner{}, dir, os.Stderr, args...)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+	cmd.WarnInvalidNotes(os.Stderr, commits)
+
+	pairings, err := pairs.Pairings(commits, *all)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	switch *format {
+	case "csv":
+		err = printCSV(pairings)
+	case "json":
+		err = printJSON(pairings)
+	default:
+		err = printTable(pairings)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+}
+
+func lastPaired(pairing *duet.Pairing) string {
+	if pairing.Last.IsZero() {
+		return "never"
+	}
+	return pairing.Last.Format(dateFormat)
+}
+
+func printTable(pairings []*duet.Pairing) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PAIR\tCOMMITS\tLAST PAIRED")
+	for _, p := range pairings {
+		fmt.Fprintf(w, "%s %s\t%d\t%s\n", p.People[0], p.People[1], p.Commits, lastPaired(p))
+	}
+	return w.Flush()
+}
+
+func printCSV(pairings []*duet.Pairing) error {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"first", "second", "commits", "last_paired"})
+	for _, p := range pairings {
+		w.Write([]string{p.People[0], p.People[1], fmt.Sprint(p.Commits), lastPaired(p)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func printJSON(pairings []*duet.Pairing) error {
+	out := []jsonPairing{}
+	for _, p := range pairings {
+		pairing := jsonPairing{Pair: p.People, Commits: p.Commits}
+		if !p.Last.IsZero() {
+			last := p.Last
+			pairing.LastPaired = &last
+		}
+		out = append(out, pairing)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
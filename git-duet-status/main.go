@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	duet "github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/internal/cmd"
+	"github.com/pborman/getopt"
+)
+
+func main() {
+	var (
+		workspace = getopt.StringLong("workspace", 'w', "", "Show every repository of a workspace (directory or manifest file)", "path")
+		help      = getopt.BoolLong("help", 'h', "Help")
+		directory = cmd.DirOption(nil)
+	)
+
+	getopt.SetProgram("git duet status")
+	getopt.SetParameters("[<initials>...]")
+	getopt.Parse()
+	dir := string(*directory)
+
+	if *help {
+		getopt.Usage()
+		os.Exit(0)
+	}
+
+	configuration, err := (&duet.Loader{Dir: dir}).Load(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	var repos []string
+	var ws *duet.Workspace
+	if *workspace != "" {
+		if ws, err = duet.LoadWorkspace(directory.Path(*workspace)); err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+		repos = ws.Repos
+	} else {
+		toplevel, err := duet.TopLevel(context.Background(), duet.ExecRunner{}, dir)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+		repos = []string{toplevel}
+	}
+
+	// the intended pairing is the one given, or the one last set for the
+	// workspace
+	intended := getopt.Args()
+	if len(intended) > 0 {
+		pairs, err := configuration.Pairs()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+		if intended, err = pairs.Expand(intended...); err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+	} else if ws != nil {
+		gitConfig := &duet.GitConfig{Namespace: configuration.Namespace, Scope: duet.Global}
+		if intended, err = gitConfig.GetWorkspacePairing(ws); err != nil {
+			fmt.Println(err)
+			os.Exit(duet.ExitCode(err))
+		}
+	}
+
+	if len(intended) > 0 {
+		fmt.Printf("intended pairing: %s\n", strings.Join(intended, " "))
+	}
+
+	drifted := false
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	report := func(name string, pairing []string, err error, intended []string) {
+		status := pairingStatus(pairing, err, intended)
+		if status != "ok" && status != "" {
+			drifted = true
+		}
+
+		shown := strings.Join(pairing, " ")
+		if shown == "" {
+			shown = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, shown, status)
+	}
+
+	for _, repo := range repos {
+		name := repo
+		if ws != nil {
+			name = ws.Rel(repo)
+		}
+
+		pairing, err := repoPairing(repo, configuration.Namespace)
+		report(name, pairing, err, intended)
+
+		// submodules are expected to be paired like their superproject
+		submodules, err := duet.Submodules(context.Background(), duet.ExecRunner{}, repo)
+		if err != nil {
+			continue
+		}
+		superproject := intended
+		if len(superproject) == 0 {
+			superproject = pairing
+		}
+		for _, submodule := range submodules {
+			pairing, err := repoPairing(submodule.Dir, configuration.Namespace)
+			report(filepath.Join(name, submodule.Path), pairing, err, superproject)
+		}
+	}
+	if err = w.Flush(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if drifted {
+		os.Exit(1)
+	}
+}
+
+// pairingStatus returns how a pairing compares to the intended one ("" if
+// there's none)
+func pairingStatus(pairing []string, err error, intended []string) string {
+	switch {
+	case errors.Is(err, duet.ErrAuthorNotSet):
+		return "not paired"
+	case err != nil:
+		return err.Error()
+	case len(intended) == 0:
+		return ""
+	case !duet.SamePairing(pairing, intended):
+		return "drifted"
+	}
+	return "ok"
+}
+
+// repoPairing returns the initials of the pairing set for a repository,
+// author first
+func repoPairing(repo, namespace string) (initials []string, err error) {
+	stderr := new(bytes.Buffer)
+	gitConfig, err := (&duet.GitConfig{
+		Namespace: namespace,
+		Dir:       repo,
+		Stdout:    ioutil.Discard,
+		Stderr:    stderr,
+	}).AuthorConfig()
+	if err != nil {
+		return nil, cmd.GitError(err, stderr.String())
+	}
+
+	author, err := gitConfig.GetAuthor()
+	if err != nil {
+		return nil, cmd.GitError(err, stderr.String())
+	}
+	committers, err := gitConfig.GetCommitters()
+	if err != nil {
+		return nil, cmd.GitError(err, stderr.String())
+	}
+
+	initials = []string{author.Initials}
+	for _, committer := range committers {
+		initials = append(initials, committer.Initials)
+	}
+	return initials, nil
+}
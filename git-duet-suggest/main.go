@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/internal/cmd"
+	"github.com/pborman/getopt"
+)
+
+func main() {
+	var (
+		never     = getopt.ListLong("never", 'n', "Pairs that must not pair, e.g. jd:fb,al:on")
+		seniors   = getopt.ListLong("seniors", 0, "Initials of seniors, each pair needs one of them")
+		since     = getopt.StringLong("since", 0, "", "Only consider pairings more recent than a date")
+		help      = getopt.BoolLong("help", 'h', "Help")
+		directory = cmd.DirOption(nil)
+	)
+
+	getopt.SetParameters("<initials|team>...")
+	getopt.Parse()
+	dir := string(*directory)
+
+	if *help {
+		getopt.Usage()
+		os.Exit(0)
+	}
+
+	configuration, err := (&duet.Loader{Dir: dir}).Load(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	pairs, err := configuration.Pairs()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	people, err := pairs.Expand(getopt.Args()...)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+	if len(people) < 2 {
+		fmt.Println("must specify at least two people")
+		os.Exit(1)
+	}
+
+	planner := &planner{
+		last:    map[[2]string]int64{},
+		never:   map[[2]string]bool{},
+		seniors: map[string]bool{},
+	}
+
+	for _, n := range *never {
+		initials := strings.Split(n, ":")
+		if len(initials) != 2 {
+			fmt.Printf("invalid pair %s (expected initials:initials)\n", n)
+			os.Exit(1)
+		}
+		planner.never[key(initials[0], initials[1])] = true
+	}
+	for _, s := range *seniors {
+		planner.seniors[s] = true
+	}
+
+	var args []string
+	if *since != "" {
+		args = append(args, "--since="+*since)
+	}
+	commits, err := duet.ReadHistory(context.Background(), duet.ExecRunner{}, dir, os.Stderr, args...)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+	cmd.WarnInvalidNotes(os.Stderr, commits)
+
+	pairings, err := pairs.Pairings(commits, false)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+	for _, p := range pairings {
+		planner.last[p.People] = p.Last.Unix()
+	}
+
+	groups, err := planner.plan(people)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	for _, group := range groups {
+		fmt.Printf("git duet %s\n", strings.Join(group, " "))
+	}
+}
+
+// planner splits people into pairs (plus a trio if there is an odd number
+// of them) so that the sum of when each two people in a group last paired is
+// as small as possible, i.e. favoring pairs who paired least recently
+type planner struct {
+	last    map[[2]string]int64
+	never   map[[2]string]bool
+	seniors map[string]bool
+
+	best     [][]string
+	bestCost int64
+}
+
+func (p *planner) plan(people []string) (groups [][]string, err error) {
+	p.best = nil
+	p.search(people, nil, 0, len(people)%2 == 1)
+	if p.best == nil {
+		return nil, errors.New("no pairing satisfies the constraints")
+	}
+	return p.best, nil
+}
+
+func (p *planner) search(remaining []string, groups [][]string, cost int64, needsTrio bool) {
+	if p.best != nil && cost >= p.bestCost {
+		return
+	}
+	if len(remaining) == 0 {
+		p.best = append([][]string{}, groups...)
+		p.bestCost = cost
+		return
+	}
+
+	first := remaining[0]
+	for i := 1; i < len(remaining); i++ {
+		pair := []string{first, remaining[i]}
+		if c, ok := p.cost(pair); ok {
+			p.search(without(remaining, 0, i), append(groups, pair), cost+c, needsTrio)
+		}
+
+		if !needsTrio {
+			continue
+		}
+		for j := i + 1; j < len(remaining); j++ {
+			trio := []string{first, remaining[i], remaining[j]}
+			if c, ok := p.cost(trio); ok {
+				p.search(without(remaining, 0, i, j), append(groups, trio), cost+c, false)
+			}
+		}
+	}
+}
+
+// cost returns the sum of when each two people in the group last paired
+// (zero if they never did) and whether the group satisfies the constraints
+func (p *planner) cost(group []string) (cost int64, ok bool) {
+	hasSenior := len(p.seniors) == 0
+	for i := range group {
+		hasSenior = hasSenior || p.seniors[group[i]]
+		for j := i + 1; j < len(group); j++ {
+			if p.never[key(group[i], group[j])] {
+				return 0, false
+			}
+			cost += p.last[key(group[i], group[j])]
+		}
+	}
+	return cost, hasSenior
+}
+
+func without(people []string, indexes ...int) (rest []string) {
+	skip := map[int]bool{}
+	for _, i := range indexes {
+		skip[i] = true
+	}
+	for i, person := range people {
+		if !skip[i] {
+			rest = append(rest, person)
+		}
+	}
+	return rest
+}
+
+func key(a, b string) [2]string {
+	if b < a {
+		return [2]string{b, a}
+	}
+	return [2]string{a, b}
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 
 	duet "github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/internal/cmd"
 	"github.com/pborman/getopt"
 )
 
@@ -16,16 +19,44 @@ var (
 	RevisionString string
 )
 
+// subcommands are run as `git duet <subcommand>` and implemented by the
+// git-duet-<subcommand> executable
+var subcommands = map[string]bool{
+	"authors":    true,
+	"blame":      true,
+	"board":      true,
+	"cache":      true,
+	"completion": true,
+	"config":     true,
+	"mailmap":    true,
+	"shortlog":   true,
+	"stats":      true,
+	"status":     true,
+	"suggest":    true,
+}
+
 func main() {
 	var (
-		quiet   = getopt.BoolLong("quiet", 'q', "Silence output")
-		global  = getopt.BoolLong("global", 'g', "Change global config")
-		help    = getopt.BoolLong("help", 'h', "Help")
-		version = getopt.BoolLong("version", 'v', "Version")
-		show    = getopt.BoolLong("show", 's', "Show")
+		quiet     = getopt.BoolLong("quiet", 'q', "Silence output")
+		global    = getopt.BoolLong("global", 'g', "Change global config")
+		help      = getopt.BoolLong("help", 'h', "Help")
+		version   = getopt.BoolLong("version", 'v', "Version")
+		show      = getopt.BoolLong("show", 's', "Show")
+		pick      = getopt.BoolLong("pick", 'i', "Pick the pairing interactively")
+		workspace = getopt.StringLong("workspace", 'w', "", "Set the pairing in every repository of a workspace (directory or manifest file)", "path")
+		recurse   = getopt.BoolLong("recurse-submodules", 0, "Also set the pairing in the initialized submodules")
+		refresh   = getopt.BoolLong("refresh", 0, "Run the email lookup again instead of using cached emails")
+		install   = getopt.ListLong("install-hooks", 0, "Also install the hooks (pre-commit, prepare-commit-msg or post-commit, comma separated), in every repository with --workspace", "hook,...")
+		directory = cmd.DirOption(nil)
 	)
 
 	getopt.Parse()
+	dir := string(*directory)
+
+	// -C before a subcommand applies to it
+	if getopt.NArgs() > 0 && subcommands[getopt.Arg(0)] {
+		runSubcommand(dir, getopt.Arg(0), getopt.Args()[1:])
+	}
 
 	if *help {
 		getopt.Usage()
@@ -37,32 +68,52 @@ func main() {
 		os.Exit(0)
 	}
 
-	configuration, err := duet.NewConfiguration()
+	configuration, err := (&duet.Loader{Dir: dir}).Load(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+	configuration.RefreshEmailLookup = *refresh
+
+	hooks, err := duetHooks(configuration, *install)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	gitConfig := &duet.GitConfig{Namespace: configuration.Namespace, SetUserConfig: configuration.SetGitUserConfig}
+	gitConfig := &duet.GitConfig{Namespace: configuration.Namespace, SetUserConfig: configuration.SetGitUserConfig, Dir: dir}
 	if *global || configuration.Global {
 		gitConfig.Scope = duet.Global
 	}
 
-	if (configuration.DefaultUpdate && getopt.NArgs() == 0) || (getopt.NArgs() != 0 && getopt.NArgs() < 2) {
+	initials := getopt.Args()
+	if *pick {
+		initials = pickInitials(configuration, gitConfig)
+	}
+
+	if (configuration.DefaultUpdate && len(initials) == 0) || (len(initials) != 0 && len(initials) < 2) {
 		fmt.Println("must specify at least two sets of initials")
 		os.Exit(1)
 	}
 
-	if getopt.NArgs() == 0 || *show {
+	if *workspace != "" {
+		if len(initials) == 0 {
+			fmt.Println("must specify at least two sets of initials")
+			os.Exit(1)
+		}
+		os.Exit(pairWorkspace(directory.Path(*workspace), configuration, hooks, initials, *quiet))
+	}
+
+	if len(initials) == 0 || *show {
 		author, err := gitConfig.GetAuthor()
 		if err != nil {
 			fmt.Println(err)
-			os.Exit(1)
+			os.Exit(duet.ExitCode(err))
 		}
 		committers, err := gitConfig.GetCommitters()
 		if err != nil {
 			fmt.Println(err)
-			os.Exit(1)
+			os.Exit(duet.ExitCode(err))
 		}
 
 		if committers == nil && author != nil {
@@ -75,45 +126,42 @@ func main() {
 		} else {
 			printNextComitter(committers)
 		}
-		if configuration.CoAuthoredBy {
-			installHook("prepare-commit-msg")
-			// SetAuthor is needed in case neither GIT_DUET_CO_AUTHORED_BY nor GIT_DUET_SET_GIT_USER_CONFIG was set previously
-			if author != nil {
-				if err = gitConfig.SetAuthor(author); err != nil {
-					fmt.Println(err)
-					os.Exit(1)
-				}
-			}
-			if configuration.RotateAuthor {
-				installHook("post-commit")
+		for _, hook := range hooks {
+			installHook(dir, hook, *recurse)
+		}
+		// SetAuthor is needed in case neither GIT_DUET_CO_AUTHORED_BY nor GIT_DUET_SET_GIT_USER_CONFIG was set previously
+		if configuration.CoAuthoredBy && author != nil {
+			if err = gitConfig.SetAuthor(author); err != nil {
+				fmt.Println(err)
+				os.Exit(duet.ExitCode(err))
 			}
 		}
 		os.Exit(0)
 	}
 
-	pairs, err := duet.NewPairsFromFile(configuration.PairsFile, configuration.EmailLookup)
+	pairs, err := configuration.Pairs()
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(0)
+		os.Exit(duet.ExitCode(err))
 	}
 
-	author, err := pairs.ByInitials(getopt.Arg(0))
+	author, err := pairs.Resolve(initials[0])
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(86)
+		os.Exit(duet.ExitCode(err))
 	}
 	if err = gitConfig.SetAuthor(author); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
 
 	var committers []*duet.Pair
 
-	for _, initials := range getopt.Args()[1:] {
-		committer, err := pairs.ByInitials(initials)
+	for _, i := range initials[1:] {
+		committer, err := pairs.Resolve(i)
 		if err != nil {
 			fmt.Println(err)
-			os.Exit(86)
+			os.Exit(duet.ExitCode(err))
 		}
 
 		committers = append(committers, committer)
@@ -121,7 +169,16 @@ func main() {
 
 	if err = gitConfig.SetCommitters(committers...); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	pairing := []string{author.Initials}
+	for _, committer := range committers {
+		pairing = append(pairing, committer.Initials)
+	}
+	if err = gitConfig.AddRecentPairing(pairing...); err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
 	}
 
 	if !*quiet {
@@ -133,12 +190,70 @@ func main() {
 		}
 	}
 
+	for _, hook := range hooks {
+		installHook(dir, hook, *recurse)
+	}
+
+	// submodules read the global config too, only a local pairing needs
+	// copying (the hooks are installed in them already)
+	if *recurse && gitConfig.Scope != duet.Global {
+		os.Exit(cmd.PairSubmodules(context.Background(), duet.ExecRunner{}, dir, func(dir string) error {
+			return pairRepo(dir, configuration, nil, author, committers)
+		}))
+	}
+}
+
+// duetHooks returns the hooks git duet installs: the ones the configuration
+// needs, prepare-commit-msg (and post-commit to rotate, track staleness or
+// write notes) with GIT_DUET_CO_AUTHORED_BY, and the ones asked for
+func duetHooks(configuration *duet.Configuration, install []string) (hooks []string, err error) {
 	if configuration.CoAuthoredBy {
-		installHook("prepare-commit-msg")
-		if configuration.RotateAuthor {
-			installHook("post-commit")
+		hooks = append(hooks, "prepare-commit-msg")
+		if configuration.RotateAuthor || configuration.StaleSince == duet.StaleSinceCommit || configuration.Notes {
+			hooks = append(hooks, "post-commit")
+		}
+	}
+
+	for _, hook := range install {
+		known := false
+		for _, h := range cmd.Hooks {
+			known = known || h == hook
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown hook %s (expected %s)", hook, strings.Join(cmd.Hooks, ", "))
+		}
+
+		installed := false
+		for _, h := range hooks {
+			installed = installed || h == hook
+		}
+		if !installed {
+			hooks = append(hooks, hook)
 		}
 	}
+	return hooks, nil
+}
+
+// pickInitials runs the pair picker over the authors file
+func pickInitials(configuration *duet.Configuration, gitConfig *duet.GitConfig) []string {
+	pairs, err := configuration.Pairs()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	recent, err := gitConfig.GetRecentPairings()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	initials, err := pickPairing(pairs, recent)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+	return initials
 }
 
 func printAuthor(author *duet.Pair) {
@@ -170,14 +285,31 @@ func printAllCommitters(committers []*duet.Pair) {
 	}
 }
 
-func installHook(hookType string) {
-	cmd := exec.Command("git-duet-install-hook", hookType)
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
+// installHook installs a hook in the repository containing dir, and in its
+// submodules if recurse is set
+func installHook(dir, hookType string, recurse bool) {
+	if err := cmd.InstallHook(context.Background(), duet.ExecRunner{}, dir, hookType, recurse); err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
+	}
+}
 
-	err := cmd.Run()
+// runSubcommand runs git-duet-<subcommand> in dir and exits with its status
+func runSubcommand(dir, subcommand string, args []string) {
+	err := duet.ExecRunner{}.Run(context.Background(), &duet.Command{
+		Name:   "git-duet-" + subcommand,
+		Args:   args,
+		Dir:    dir,
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		os.Exit(exitErr.ExitCode())
+	}
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
+	os.Exit(0)
 }
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	duet "github.com/git-duet/git-duet"
+)
+
+// pickHeight is the number of choices shown at once by the picker
+const pickHeight = 10
+
+var errPickCancelled = errors.New("no pairing picked")
+
+// choice is a line of the picker: an author or a recently used pairing
+type choice struct {
+	initials []string
+	label    string
+	recent   bool
+}
+
+// pickPairing lets the user pick the pairing, author first, from the recently
+// used pairings and the authors file
+// The choices only take names, the email lookup runs for the picked initials
+// alone afterwards
+// A picker is shown when stdin is a terminal, a plain prompt otherwise
+func pickPairing(pairs *duet.Pairs, recent [][]string) (initials []string, err error) {
+	people := pairs.Candidates()
+
+	names := map[string]string{}
+	for _, p := range people {
+		names[p.Initials] = p.Name
+	}
+
+	var choices []choice
+recent:
+	for _, r := range recent {
+		var pairing []string
+		for _, i := range r {
+			if _, ok := names[i]; !ok {
+				continue recent
+			}
+			pairing = append(pairing, names[i])
+		}
+		choices = append(choices, choice{
+			initials: r,
+			label:    fmt.Sprintf("%s  %s", strings.Join(r, " "), strings.Join(pairing, ", ")),
+			recent:   true,
+		})
+	}
+	for _, p := range people {
+		choices = append(choices, choice{
+			initials: []string{p.Initials},
+			label:    fmt.Sprintf("%s  %s", p.Initials, p.Name),
+		})
+	}
+
+	if restore, err := makeRaw(os.Stdin); err == nil {
+		defer restore()
+		return (&picker{choices: choices, in: os.Stdin, out: os.Stderr}).run()
+	}
+
+	return prompt(pairs, choices, os.Stdin, os.Stderr)
+}
+
+// makeRaw switches the terminal to reading single keys without echo, and
+// returns a function restoring its previous state
+func makeRaw(tty *os.File) (restore func(), err error) {
+	if fi, err := tty.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil, errors.New("not a terminal")
+	}
+
+	state, err := stty(tty, "-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = stty(tty, "-icanon", "-echo", "-isig", "min", "1", "time", "0"); err != nil {
+		return nil, err
+	}
+
+	return func() { stty(tty, state) }, nil
+}
+
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// picker is a type-to-filter list where space adds authors to the pairing in
+// order and enter confirms it
+type picker struct {
+	choices []choice
+	in      io.Reader
+	out     io.Writer
+
+	filter   string
+	cursor   int
+	selected []string
+	message  string
+	drawn    int
+}
+
+func (p *picker) run() (initials []string, err error) {
+	buf := make([]byte, 64)
+	for {
+		p.draw()
+
+		n, err := p.in.Read(buf)
+		if err != nil {
+			p.clear()
+			return nil, errPickCancelled
+		}
+
+		for i := 0; i < n; i++ {
+			p.message = ""
+			switch b := buf[i]; {
+			case b == 27 && i+2 < n && buf[i+1] == '[':
+				switch buf[i+2] {
+				case 'A':
+					p.move(-1)
+				case 'B':
+					p.move(1)
+				}
+				i += 2
+			case b == 27, b == 3, b == 4:
+				p.clear()
+				return nil, errPickCancelled
+			case b == 16:
+				p.move(-1)
+			case b == 14:
+				p.move(1)
+			case b == ' ', b == '\t':
+				p.toggle()
+			case b == '\r', b == '\n':
+				if initials := p.confirm(); initials != nil {
+					p.clear()
+					return initials, nil
+				}
+			case b == 127, b == 8:
+				if p.filter != "" {
+					p.filter = p.filter[:len(p.filter)-1]
+					p.cursor = 0
+				}
+			case b > ' ' && b < 127:
+				p.filter += string(b)
+				p.cursor = 0
+			}
+		}
+	}
+}
+
+// visible returns the choices matching the filter
+func (p *picker) visible() (visible []choice) {
+	filter := strings.ToLower(p.filter)
+	for _, c := range p.choices {
+		if strings.Contains(strings.ToLower(c.label), filter) {
+			visible = append(visible, c)
+		}
+	}
+	return visible
+}
+
+func (p *picker) current() *choice {
+	visible := p.visible()
+	if p.cursor >= len(visible) {
+		return nil
+	}
+	return &visible[p.cursor]
+}
+
+func (p *picker) move(by int) {
+	p.cursor += by
+	if n := len(p.visible()); p.cursor >= n {
+		p.cursor = n - 1
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+}
+
+func (p *picker) position(initials string) int {
+	for i, s := range p.selected {
+		if s == initials {
+			return i
+		}
+	}
+	return -1
+}
+
+// toggle adds the author under the cursor to the pairing (or removes them),
+// a recent pairing replaces the selection
+func (p *picker) toggle() {
+	c := p.current()
+	switch {
+	case c == nil:
+	case c.recent:
+		p.selected = append([]string{}, c.initials...)
+	case p.position(c.initials[0]) >= 0:
+		i := p.position(c.initials[0])
+		p.selected = append(p.selected[:i], p.selected[i+1:]...)
+	default:
+		p.selected = append(p.selected, c.initials[0])
+	}
+}
+
+// confirm returns the picked pairing, taking the choice under the cursor into
+// account, or nil if there are not enough authors yet
+func (p *picker) confirm() []string {
+	c := p.current()
+	switch {
+	case c != nil && c.recent && len(p.selected) == 0:
+		return c.initials
+	case c != nil && !c.recent && p.position(c.initials[0]) < 0:
+		p.selected = append(p.selected, c.initials[0])
+	}
+
+	if len(p.selected) < 2 {
+		p.message = "select at least two authors"
+		return nil
+	}
+	return p.selected
+}
+
+func (p *picker) draw() {
+	p.clear()
+
+	lines := []string{
+		"pick the pairing, author first (type to filter, space to select, enter to confirm)",
+		"> " + p.filter,
+	}
+
+	visible := p.visible()
+	start := 0
+	if p.cursor >= pickHeight {
+		start = p.cursor - pickHeight + 1
+	}
+	for i := start; i < len(visible) && i < start+pickHeight; i++ {
+		c := visible[i]
+		cursor := "  "
+		if i == p.cursor {
+			cursor = "> "
+		}
+
+		mark := "[ ]"
+		if c.recent {
+			mark = " * "
+		} else if n := p.position(c.initials[0]); n >= 0 {
+			mark = fmt.Sprintf("[%d]", n+1)
+		}
+		lines = append(lines, cursor+mark+" "+c.label)
+	}
+
+	status := "selected: " + strings.Join(p.selected, " ")
+	if p.message != "" {
+		status = p.message
+	}
+	lines = append(lines, status)
+
+	fmt.Fprint(p.out, strings.Join(lines, "\n"))
+	p.drawn = len(lines)
+}
+
+// clear erases what was drawn last
+func (p *picker) clear() {
+	if p.drawn == 0 {
+		return
+	}
+	if p.drawn > 1 {
+		fmt.Fprintf(p.out, "\x1b[%dA", p.drawn-1)
+	}
+	fmt.Fprint(p.out, "\r\x1b[J")
+	p.drawn = 0
+}
+
+// prompt asks for the pairing as initials, names or numbers of recent
+// pairings until it gets a valid one
+func prompt(pairs *duet.Pairs, choices []choice, in io.Reader, out io.Writer) (initials []string, err error) {
+	var recent []choice
+	for _, c := range choices {
+		if c.recent {
+			recent = append(recent, c)
+		}
+	}
+
+	if len(recent) > 0 {
+		fmt.Fprintln(out, "recent pairings:")
+		for i, c := range recent {
+			fmt.Fprintf(out, "  %d) %s\n", i+1, c.label)
+		}
+	}
+	fmt.Fprintln(out, "authors:")
+	for _, c := range choices {
+		if !c.recent {
+			fmt.Fprintf(out, "  %s\n", c.label)
+		}
+	}
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "pick the pairing, author first (initials, names or a number): ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return nil, errPickCancelled
+		}
+
+		var names []string
+		for _, field := range strings.Fields(scanner.Text()) {
+			if n, err := strconv.Atoi(field); err == nil && n > 0 && n <= len(recent) {
+				names = append(names, recent[n-1].initials...)
+			} else {
+				names = append(names, field)
+			}
+		}
+
+		initials, err := pairs.Expand(names...)
+		switch {
+		case err != nil:
+			fmt.Fprintln(out, err)
+		case len(initials) < 2:
+			fmt.Fprintln(out, "must specify at least two sets of initials")
+		default:
+			return initials, nil
+		}
+	}
+}
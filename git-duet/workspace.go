@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	duet "github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/internal/cmd"
+)
+
+// pairWorkspace sets the pairing in the local config of every repository of
+// the workspace and installs the hooks there, reporting how it went for each,
+// and returns the exit code (the one of the first failure)
+// The pairing is recorded for the workspace in the global config, see
+// GitConfig.SetWorkspacePairing
+func pairWorkspace(path string, configuration *duet.Configuration, hooks []string, initials []string, quiet bool) int {
+	workspace, err := duet.LoadWorkspace(path)
+	if err != nil {
+		fmt.Println(err)
+		return duet.ExitCode(err)
+	}
+
+	pairs, err := configuration.Pairs()
+	if err != nil {
+		fmt.Println(err)
+		return duet.ExitCode(err)
+	}
+
+	var people []*duet.Pair
+	for _, i := range initials {
+		person, err := pairs.Resolve(i)
+		if err != nil {
+			fmt.Println(err)
+			return duet.ExitCode(err)
+		}
+		people = append(people, person)
+	}
+
+	var failed error
+	for _, repo := range workspace.Repos {
+		if err := pairRepo(repo, configuration, hooks, people[0], people[1:]); err != nil {
+			fmt.Printf("%s: %s\n", workspace.Rel(repo), err)
+			if failed == nil {
+				failed = err
+			}
+		} else if !quiet {
+			fmt.Printf("%s: ok\n", workspace.Rel(repo))
+		}
+	}
+
+	var pairing []string
+	for _, person := range people {
+		pairing = append(pairing, person.Initials)
+	}
+	gitConfig := &duet.GitConfig{Namespace: configuration.Namespace, Scope: duet.Global}
+	if err = gitConfig.SetWorkspacePairing(workspace, pairing...); err != nil {
+		fmt.Println(err)
+		return duet.ExitCode(err)
+	}
+
+	return duet.ExitCode(failed)
+}
+
+// pairRepo sets the pairing in the local config of a repository, and installs
+// the hooks there
+func pairRepo(repo string, configuration *duet.Configuration, hooks []string, author *duet.Pair, committers []*duet.Pair) error {
+	stderr := new(bytes.Buffer)
+	gitConfig := &duet.GitConfig{
+		Namespace:     configuration.Namespace,
+		Scope:         duet.Local,
+		SetUserConfig: configuration.SetGitUserConfig,
+		Dir:           repo,
+		Stdout:        ioutil.Discard,
+		Stderr:        stderr,
+	}
+
+	if err := gitConfig.SetAuthor(author); err != nil {
+		return cmd.GitError(err, stderr.String())
+	}
+	if err := gitConfig.SetCommitters(committers...); err != nil {
+		return cmd.GitError(err, stderr.String())
+	}
+
+	for _, hook := range hooks {
+		output := new(bytes.Buffer)
+		err := duet.ExecRunner{}.Run(context.Background(), &duet.Command{
+			Name:   "git-duet-install-hook",
+			Args:   []string{"-q", hook},
+			Dir:    repo,
+			Stdout: output,
+			Stderr: output,
+		})
+		if err != nil {
+			return cmd.GitError(err, output.String())
+		}
+	}
+
+	return nil
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
 	"github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/internal/cmd"
 	"github.com/pborman/getopt"
 )
 
@@ -17,14 +19,18 @@ var (
 
 func main() {
 	var (
-		quiet   = getopt.BoolLong("quiet", 'q', "Silence output")
-		global  = getopt.BoolLong("global", 'g', "Change global config")
-		help    = getopt.BoolLong("help", 'h', "Help")
-		version = getopt.BoolLong("version", 'v', "Version")
-		show 	= getopt.BoolLong("show", 's', "Show")
+		quiet     = getopt.BoolLong("quiet", 'q', "Silence output")
+		global    = getopt.BoolLong("global", 'g', "Change global config")
+		help      = getopt.BoolLong("help", 'h', "Help")
+		version   = getopt.BoolLong("version", 'v', "Version")
+		show      = getopt.BoolLong("show", 's', "Show")
+		recurse   = getopt.BoolLong("recurse-submodules", 0, "Also set the author in the initialized submodules")
+		refresh   = getopt.BoolLong("refresh", 0, "Run the email lookup again instead of using cached emails")
+		directory = cmd.DirOption(nil)
 	)
 
 	getopt.Parse()
+	dir := string(*directory)
 
 	if *help {
 		getopt.Usage()
@@ -36,13 +42,14 @@ func main() {
 		os.Exit(0)
 	}
 
-	configuration, err := duet.NewConfiguration()
+	configuration, err := (&duet.Loader{Dir: dir}).Load(context.Background())
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
+	configuration.RefreshEmailLookup = *refresh
 
-	gitConfig := &duet.GitConfig{Namespace: configuration.Namespace, SetUserConfig: configuration.SetGitUserConfig}
+	gitConfig := &duet.GitConfig{Namespace: configuration.Namespace, SetUserConfig: configuration.SetGitUserConfig, Dir: dir}
 	if *global || configuration.Global {
 		gitConfig.Scope = duet.Global
 	}
@@ -56,11 +63,11 @@ func main() {
 		if configuration.DefaultUpdate && (*global || configuration.Global || configuration.IsCurrentWorkingDirGitRepo) {
 			if err = gitConfig.ClearCommitter(); err != nil {
 				fmt.Println(err)
-				os.Exit(1)
+				os.Exit(duet.ExitCode(err))
 			}
 			if err = gitConfig.ClearAuthor(); err != nil {
 				fmt.Println(err)
-				os.Exit(1)
+				os.Exit(duet.ExitCode(err))
 			}
 		} else if configuration.DefaultUpdate && !configuration.IsCurrentWorkingDirGitRepo {
 			fmt.Println("must run on a git repository. No git configuration to reset.")
@@ -71,44 +78,63 @@ func main() {
 		os.Exit(0)
 	}
 
-	pairs, err := duet.NewPairsFromFile(configuration.PairsFile, configuration.EmailLookup)
+	pairs, err := configuration.Pairs()
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(0)
+		os.Exit(duet.ExitCode(err))
 	}
 
-	author, err := pairs.ByInitials(getopt.Arg(0))
+	author, err := pairs.Resolve(getopt.Arg(0))
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(86)
+		os.Exit(duet.ExitCode(err))
 	}
 
 	if err = gitConfig.SetAuthor(author); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
+	}
+
+	if err = gitConfig.SetSoloist(author); err != nil {
+		fmt.Println(err)
+		os.Exit(duet.ExitCode(err))
 	}
 
 	if err = gitConfig.ClearCommitter(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
 
 	if !*quiet {
 		printAuthorAndCommitter(gitConfig)
 	}
+
+	// submodules read the global config too, only a local author needs
+	// copying
+	if *recurse && gitConfig.Scope != duet.Global {
+		os.Exit(cmd.SetSubmodules(context.Background(), gitConfig, func(submodule *duet.GitConfig) error {
+			if err := submodule.SetAuthor(author); err != nil {
+				return err
+			}
+			if err := submodule.SetSoloist(author); err != nil {
+				return err
+			}
+			return submodule.ClearCommitter()
+		}))
+	}
 }
 
 func printAuthorAndCommitter(gitConfig *duet.GitConfig) {
 	author, err := gitConfig.GetAuthor()
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
 
 	committers, err := gitConfig.GetCommitters()
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(duet.ExitCode(err))
 	}
 	if committers == nil && author != nil {
 		committers = []*duet.Pair{author}
@@ -135,4 +161,3 @@ func printNextCommitter(committers []*duet.Pair) {
 	fmt.Printf("GIT_COMMITTER_NAME='%s'\n", committers[0].Name)
 	fmt.Printf("GIT_COMMITTER_EMAIL='%s'\n", committers[0].Email)
 }
-
@@ -2,10 +2,10 @@ package duet
 
 import (
 	"bytes"
-	"errors"
+	"context"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
@@ -34,21 +34,51 @@ const (
 // Namespace determines the section under which configuration will be stored
 // SetUserConfig determines whether user.name and user.email are set in
 // addition to the git-duet namespaced configuration for the author
+// Dir is the repository whose config is used (the current directory if empty)
+// Stdout and Stderr receive the output of git (os.Stdout and os.Stderr if nil)
+// Runner runs git (ExecRunner if nil)
 type GitConfig struct {
 	Namespace string
 	Scope     scope
 
 	SetUserConfig bool
+
+	Dir    string
+	Stdout io.Writer
+	Stderr io.Writer
+	Runner Runner
+
+	ctx context.Context
+}
+
+// WithContext returns a copy of the GitConfig running git with the given
+// context, so that it's killed once the context is done
+func (gc *GitConfig) WithContext(ctx context.Context) *GitConfig {
+	config := *gc
+	config.ctx = ctx
+	return &config
+}
+
+func (gc *GitConfig) context() context.Context {
+	if gc.ctx == nil {
+		return context.Background()
+	}
+	return gc.ctx
 }
 
 // GetAuthorConfig returns the config source for git author information.
 func GetAuthorConfig(namespace string, setUserConfig bool) (config *GitConfig, err error) {
-	configs := []*GitConfig{
-		{Namespace: namespace, SetUserConfig: setUserConfig, Scope: Local},
-		{Namespace: namespace, SetUserConfig: setUserConfig, Scope: Global},
-	}
+	return (&GitConfig{Namespace: namespace, SetUserConfig: setUserConfig}).AuthorConfig()
+}
 
-	for _, config := range configs {
+// AuthorConfig returns a copy of the GitConfig for the scope the author is
+// set in, the local config if it's set there, the global one otherwise
+// Returns ErrAuthorNotSet if it's set in neither
+func (gc *GitConfig) AuthorConfig() (config *GitConfig, err error) {
+	local, global := *gc, *gc
+	local.Scope, global.Scope = Local, Global
+
+	for _, config := range []*GitConfig{&local, &global} {
 		author, err := config.GetAuthor()
 		if err != nil {
 			return nil, err
@@ -58,7 +88,7 @@ func GetAuthorConfig(namespace string, setUserConfig bool) (config *GitConfig, e
 		}
 	}
 
-	return nil, errors.New("git-author not set")
+	return nil, ErrAuthorNotSet
 }
 
 // ClearCommitter removes committer name/email from config
@@ -74,6 +104,10 @@ func (gc *GitConfig) ClearCommitter() (err error) {
 	if err = gc.setKey("git-committer-email", ""); err != nil {
 		return err
 	}
+
+	if err = gc.unsetKey("git-committer-signingkey"); err != nil {
+		return err
+	}
 	if err = gc.updateMtime(); err != nil {
 		return err
 	}
@@ -93,6 +127,10 @@ func (gc *GitConfig) ClearAuthor() (err error) {
 	if err = gc.unsetKey("git-author-email"); err != nil {
 		return err
 	}
+
+	if err = gc.unsetKey("git-author-signingkey"); err != nil {
+		return err
+	}
 	if err = gc.updateMtime(); err != nil {
 		return err
 	}
@@ -126,38 +164,88 @@ func (gc *GitConfig) SetCommitters(committers ...*Pair) (err error) {
 	return nil
 }
 
-// RotateAuthor flips the committer and author if committer is set
-func (gc *GitConfig) RotateAuthor() (err error) {
-	gitConfig := gc
-	if gitConfig.Scope == Default {
-		// find source of configuration
-		if gitConfig, err = GetAuthorConfig(gc.Namespace, gc.SetUserConfig); err != nil {
-			return err
-		}
+// SetSoloist records the author as the last person to work solo so that
+// stale pairings can be reverted to them (see RevertToSolo)
+func (gc *GitConfig) SetSoloist(author *Pair) (err error) {
+	if author == nil {
+		return nil
 	}
 
-	var author *Pair
-	var committers []*Pair
+	if err = gc.setKey("git-solo-initials", author.Initials); err != nil {
+		return err
+	}
 
-	if author, err = gc.GetAuthor(); err != nil {
+	if err = gc.setKey("git-solo-name", author.Name); err != nil {
 		return err
 	}
-	if committers, err = gc.GetCommitters(); err != nil {
+
+	if err = gc.setKey("git-solo-email", author.Email); err != nil {
 		return err
 	}
+	return nil
+}
 
-	if committers != nil && len(committers) > 0 {
-		if _, err = gitConfig.setAuthor(committers[0]); err != nil {
-			return err
-		}
+// GetSoloist returns the last recorded soloist (nil if none)
+func (gc *GitConfig) GetSoloist() (pair *Pair, err error) {
+	initials, err := gc.getKey("git-solo-initials")
+	if err != nil {
+		return nil, err
+	}
 
-		committers = append(committers, author)
-		if err = gitConfig.setCommitters(committers[1:]); err != nil {
-			return err
+	name, err := gc.getKey("git-solo-name")
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := gc.getKey("git-solo-email")
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" || initials == "" || email == "" {
+		return nil, nil
+	}
+
+	return &Pair{
+		Initials: initials,
+		Name:     name,
+		Email:    email,
+	}, nil
+}
+
+// RevertToSolo sets the author to the last recorded soloist (or keeps the
+// current author if there is none) and removes all committers
+// Returns the resulting author
+func (gc *GitConfig) RevertToSolo() (author *Pair, err error) {
+	if author, err = gc.GetSoloist(); err != nil {
+		return nil, err
+	}
+
+	if author == nil {
+		if author, err = gc.GetAuthor(); err != nil {
+			return nil, err
 		}
 	}
 
-	return nil
+	if author == nil {
+		return nil, ErrAuthorNotSet
+	}
+
+	if err = gc.SetAuthor(author); err != nil {
+		return nil, err
+	}
+
+	if err = gc.ClearCommitter(); err != nil {
+		return nil, err
+	}
+
+	return author, nil
+}
+
+// RotateAuthor flips the committer and author if committer is set
+// (round-robin through the committers when mobbing)
+func (gc *GitConfig) RotateAuthor() (err error) {
+	return gc.RotateWith(roundRobin{})
 }
 
 func (gc *GitConfig) setAuthor(author *Pair) (updated bool, err error) {
@@ -185,6 +273,9 @@ func (gc *GitConfig) setAuthor(author *Pair) (updated bool, err error) {
 	if err = gc.setKey("git-author-email", author.Email); err != nil {
 		return false, err
 	}
+	if err = gc.setSigningKeys("git-author-signingkey", []*Pair{author}); err != nil {
+		return false, err
+	}
 	return true, nil
 }
 
@@ -208,6 +299,10 @@ func (gc *GitConfig) setCommitters(committers []*Pair) (err error) {
 		return err
 	}
 
+	if err = gc.setSigningKeys("git-committer-signingkey", committers); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -232,10 +327,16 @@ func (gc *GitConfig) GetAuthor() (pair *Pair, err error) {
 		return nil, nil
 	}
 
+	signingKey, err := gc.getKey("git-author-signingkey")
+	if err != nil {
+		return nil, err
+	}
+
 	return &Pair{
-		Initials: initials,
-		Name:     name,
-		Email:    email,
+		Initials:   initials,
+		Name:       name,
+		Email:      email,
+		SigningKey: signingKey,
 	}, nil
 }
 
@@ -259,15 +360,25 @@ func (gc *GitConfig) GetCommitters() (pairs []*Pair, err error) {
 		return nil, nil
 	}
 
+	signingKeys, err := gc.getKey("git-committer-signingkey")
+	if err != nil {
+		return nil, err
+	}
+
 	listOfInitials := strings.Split(initials, delim)
 	listOfNames := strings.Split(names, delim)
 	listOfEmails := strings.Split(emails, delim)
+	listOfSigningKeys := strings.Split(signingKeys, delim)
 	for i, n := range listOfInitials {
 		p := &Pair{
 			Initials: n,
 			Name:     listOfNames[i],
 			Email:    listOfEmails[i],
 		}
+		// pairings set before signing keys were kept have none
+		if len(listOfSigningKeys) == len(listOfInitials) {
+			p.SigningKey = listOfSigningKeys[i]
+		}
 		pairs = append(pairs, p)
 	}
 
@@ -294,6 +405,77 @@ func (gc *GitConfig) GetMtime() (mtime time.Time, err error) {
 	return time.Unix(mtimeUnix, 0), nil
 }
 
+// GetActivity returns the last time a commit was made with git-duet
+// Returns zero Time if key is missing
+func (gc *GitConfig) GetActivity() (activity time.Time, err error) {
+	activityString, err := gc.getKey("activity")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if activityString == "" {
+		return time.Time{}, nil
+	}
+
+	activityUnix, err := strconv.ParseInt(activityString, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(activityUnix, 0), nil
+}
+
+// UpdateActivity records that a commit was just made by the configured pairing
+func (gc *GitConfig) UpdateActivity() (err error) {
+	if err = gc.setKey("activity", strconv.FormatInt(time.Now().Unix(), 10)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// maxRecentPairings is the number of pairings kept by AddRecentPairing
+const maxRecentPairings = 5
+
+// AddRecentPairing records the initials of a pairing (author first) as the
+// most recently used one, keeping the last few distinct pairings
+func (gc *GitConfig) AddRecentPairing(initials ...string) (err error) {
+	recent, err := gc.GetRecentPairings()
+	if err != nil {
+		return err
+	}
+
+	pairing := strings.Join(initials, " ")
+	groups := []string{pairing}
+	for _, r := range recent {
+		if g := strings.Join(r, " "); g != pairing && len(groups) < maxRecentPairings {
+			groups = append(groups, g)
+		}
+	}
+
+	return gc.setKey("recent-pairings", strings.Join(groups, ","))
+}
+
+// GetRecentPairings returns the initials of the recently used pairings, most
+// recent first
+func (gc *GitConfig) GetRecentPairings() (pairings [][]string, err error) {
+	recent, err := gc.getKey("recent-pairings")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range strings.Split(recent, ",") {
+		if initials := strings.Fields(group); len(initials) > 0 {
+			pairings = append(pairings, initials)
+		}
+	}
+	return pairings, nil
+}
+
+// Touch marks the current author/committer as freshly written
+func (gc *GitConfig) Touch() (err error) {
+	return gc.updateMtime()
+}
+
 func (gc *GitConfig) GetInitTemplateDir() (templateDir string, err error) {
 	templateDir, err = gc.getUnnamespacedKey("init.templatedir")
 	if err != nil {
@@ -314,7 +496,7 @@ func (gc *GitConfig) getKey(key string) (value string, err error) {
 	cmd := gc.configCommand(fmt.Sprintf("%s.%s", gc.Namespace, key))
 	cmd.Stdout = output
 
-	err = newIgnorableCommand(cmd, 1).Run()
+	err = gc.run(cmd, 1)
 	if err != nil {
 		return "", err
 	}
@@ -326,7 +508,7 @@ func (gc *GitConfig) getUnnamespacedKey(key string) (value string, err error) {
 	cmd := gc.configCommand(key)
 	cmd.Stdout = output
 
-	err = newIgnorableCommand(cmd, 1).Run()
+	err = gc.run(cmd, 1)
 	if err != nil {
 		return "", err
 	}
@@ -334,9 +516,9 @@ func (gc *GitConfig) getUnnamespacedKey(key string) (value string, err error) {
 }
 
 func (gc *GitConfig) unsetKey(key string) (err error) {
-	if err = newIgnorableCommand(
+	if err = gc.run(
 		gc.configCommand("--unset-all", fmt.Sprintf("%s.%s", gc.Namespace, key)),
-		5).Run(); err != nil {
+		5); err != nil {
 		return err
 	}
 
@@ -344,15 +526,31 @@ func (gc *GitConfig) unsetKey(key string) (err error) {
 }
 
 func (gc *GitConfig) setUnnamespacedKey(key, value string) (err error) {
-	if err = gc.configCommand(key, value).Run(); err != nil {
+	if err = gc.run(gc.configCommand(key, value)); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// setSigningKeys keeps the signing keys of the pairs (from an email lookup)
+// under key, or removes it if none of them has one
+func (gc *GitConfig) setSigningKeys(key string, pairs []*Pair) (err error) {
+	var listOfSigningKeys []string
+	found := false
+	for _, p := range pairs {
+		listOfSigningKeys = append(listOfSigningKeys, p.SigningKey)
+		found = found || p.SigningKey != ""
+	}
+
+	if !found {
+		return gc.unsetKey(key)
+	}
+	return gc.setKey(key, strings.Join(listOfSigningKeys, delim))
+}
+
 func (gc *GitConfig) setKey(key, value string) (err error) {
-	if err = gc.configCommand(fmt.Sprintf("%s.%s", gc.Namespace, key), value).Run(); err != nil {
+	if err = gc.run(gc.configCommand(fmt.Sprintf("%s.%s", gc.Namespace, key), value)); err != nil {
 		return err
 	}
 
@@ -360,15 +558,15 @@ func (gc *GitConfig) setKey(key, value string) (err error) {
 }
 
 func (gc *GitConfig) updateMtime() (err error) {
-	if err = gc.configCommand(
+	if err = gc.run(gc.configCommand(
 		fmt.Sprintf("%s.%s", gc.Namespace, "mtime"),
-		strconv.FormatInt(time.Now().Unix(), 10)).Run(); err != nil {
+		strconv.FormatInt(time.Now().Unix(), 10))); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (gc *GitConfig) configCommand(args ...string) *exec.Cmd {
+func (gc *GitConfig) configCommand(args ...string) *Command {
 	config := []string{"config"}
 	switch gc.Scope {
 	case Global:
@@ -376,9 +574,22 @@ func (gc *GitConfig) configCommand(args ...string) *exec.Cmd {
 	case Local:
 		config = append(config, "--local")
 	}
-	config = append(config, args...)
-	cmd := exec.Command("git", config...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd := &Command{
+		Name:   "git",
+		Args:   append(config, args...),
+		Dir:    gc.Dir,
+		Stdout: gc.Stdout,
+		Stderr: gc.Stderr,
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
 	return cmd
 }
+
+func (gc *GitConfig) run(cmd *Command, validFailureCodes ...int) error {
+	return run(gc.context(), gc.Runner, cmd, validFailureCodes...)
+}
@@ -0,0 +1,115 @@
+package duet_test
+
+import (
+	"errors"
+	"testing"
+
+	duet "github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/duettest"
+)
+
+var jane = &duet.Pair{Initials: "jd", Name: "Jane Doe", Email: "jane@hamster.info.local"}
+
+func TestAuthorConfig(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		local bool
+	}{
+		{name: "prefers the local author", local: true},
+		{name: "falls back to the global author"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			git := duettest.NewGit("/src/project")
+			global := &duet.GitConfig{Namespace: "duet.env", Scope: duet.Global, Runner: git}
+			if err := global.SetAuthor(jane); err != nil {
+				t.Fatal(err)
+			}
+			if test.local {
+				local := &duet.GitConfig{Namespace: "duet.env", Scope: duet.Local, Runner: git}
+				if err := local.SetAuthor(jane); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			gitConfig := &duet.GitConfig{Namespace: "duet.env", Dir: "/src/project", Runner: git}
+			config, err := gitConfig.AuthorConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := duet.Global
+			if test.local {
+				want = duet.Local
+			}
+			if config.Scope != want {
+				t.Errorf("got scope %v, want %v", config.Scope, want)
+			}
+			if config.Dir != gitConfig.Dir || config.Runner != gitConfig.Runner {
+				t.Errorf("the config for the scope doesn't keep the directory and runner")
+			}
+		})
+	}
+}
+
+func TestAuthorConfigWithoutAuthor(t *testing.T) {
+	git := duettest.NewGit("/src/project")
+	git.Global["duet.env.git-author-initials"] = "jd"
+
+	_, err := (&duet.GitConfig{Namespace: "duet.env", Runner: git}).AuthorConfig()
+	if !errors.Is(err, duet.ErrAuthorNotSet) {
+		t.Errorf("got %v, want %v", err, duet.ErrAuthorNotSet)
+	}
+}
+
+func TestSetAuthorWritesTheScope(t *testing.T) {
+	git := duettest.NewGit("/src/project")
+
+	if err := (&duet.GitConfig{Namespace: "duet.env", Scope: duet.Global, Runner: git}).SetAuthor(jane); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := git.Local["duet.env.git-author-initials"]; ok {
+		t.Errorf("the global author was set locally")
+	}
+
+	if err := (&duet.GitConfig{Namespace: "duet.env", Runner: git}).SetAuthor(jane); err != nil {
+		t.Fatal(err)
+	}
+	if got := git.Local["duet.env.git-author-email"]; got != jane.Email {
+		t.Errorf("got local author email %q, want %q", got, jane.Email)
+	}
+}
+
+func TestSigningKeys(t *testing.T) {
+	git := duettest.NewGit("/src/project")
+	gitConfig := &duet.GitConfig{Namespace: "duet.env", Runner: git}
+
+	signing := &duet.Pair{Initials: "jd", Name: "Jane Doe", Email: "jane@hamster.info.local", SigningKey: "0xJANE"}
+	if err := gitConfig.SetAuthor(signing); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitConfig.SetCommitters(frances, signing); err != nil {
+		t.Fatal(err)
+	}
+
+	author, err := gitConfig.GetAuthor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if author.SigningKey != "0xJANE" {
+		t.Errorf("got author signing key %q, want 0xJANE", author.SigningKey)
+	}
+	committers, err := gitConfig.GetCommitters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if committers[0].SigningKey != "" || committers[1].SigningKey != "0xJANE" {
+		t.Errorf("got committer signing keys %q and %q, want none and 0xJANE", committers[0].SigningKey, committers[1].SigningKey)
+	}
+
+	// the key of the previous author isn't kept
+	if err = gitConfig.SetAuthor(jane); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := git.Local["duet.env.git-author-signingkey"]; ok {
+		t.Errorf("the signing key of the previous author was kept")
+	}
+}
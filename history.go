@@ -0,0 +1,139 @@
+package duet
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Identity is a name and email as recorded in git history
+type Identity struct {
+	Name  string
+	Email string
+}
+
+func (i Identity) String() string {
+	return i.Name + " <" + i.Email + ">"
+}
+
+// Commit is a commit from git history with everybody who took part in it
+// Note is its git-duet note (nil if it has none). NoteError is why a note
+// couldn't be read, Note is nil then and the commit falls back to its headers
+// and trailers rather than hiding the rest of the history
+type Commit struct {
+	Hash      string
+	Time      time.Time
+	Subject   string
+	Author    Identity
+	Committer Identity
+	CoAuthors []Identity
+	SignedOff []Identity
+	Note      *Note
+	NoteError error
+}
+
+// Participants returns the author, committer, co-authors and everyone who
+// signed off, without duplicates
+func (c *Commit) Participants() (identities []Identity) {
+	seen := map[string]bool{}
+	all := append([]Identity{c.Author, c.Committer}, c.CoAuthors...)
+	for _, identity := range append(all, c.SignedOff...) {
+		key := strings.ToLower(identity.Email)
+		if identity.Email == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		identities = append(identities, identity)
+	}
+	return identities
+}
+
+const historyFormat = "%H%x1f%ct%x1f%an%x1f%ae%x1f%cn%x1f%ce%x1f%s%x1f%(trailers:only,unfold)"
+
+var trailerRegexp = regexp.MustCompile(`^(?i)(co-authored-by|signed-off-by):\s*(.*?)\s*<(.+)>\s*$`)
+
+// ReadHistory runs `git log` in the repository containing dir (the current
+// directory if empty) with the given arguments (revisions, date ranges, `--`
+// and paths, ...) and returns the commits it lists, with their notes under
+// NotesRef
+// The warnings of git go to stderr (discarded if nil)
+func ReadHistory(ctx context.Context, runner Runner, dir string, stderr io.Writer, args ...string) (commits []*Commit, err error) {
+	// git warns about notes refs that don't exist
+	options := []string{"log", "-z", "--format=" + historyFormat}
+	err = run(ctx, runner, &Command{Name: "git", Args: []string{"rev-parse", "--quiet", "--verify", NotesRef}, Dir: dir})
+	if err == nil {
+		options = []string{"log", "-z", "--notes=" + NotesRef, "--format=" + historyFormat + "%x1f%N"}
+	}
+
+	output := new(bytes.Buffer)
+	err = run(ctx, runner, &Command{
+		Name:   "git",
+		Args:   append(options, args...),
+		Dir:    dir,
+		Stdout: output,
+		Stderr: stderr,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range strings.Split(output.String(), "\x00") {
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+
+		commit, err := parseCommit(record)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, commit)
+	}
+
+	return commits, nil
+}
+
+func parseCommit(record string) (commit *Commit, err error) {
+	fields := strings.SplitN(strings.TrimLeft(record, "\n"), "\x1f", 9)
+	for len(fields) < 9 {
+		fields = append(fields, "")
+	}
+
+	timestamp, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	commit = &Commit{
+		Hash:      fields[0],
+		Time:      time.Unix(timestamp, 0),
+		Author:    Identity{Name: fields[2], Email: fields[3]},
+		Committer: Identity{Name: fields[4], Email: fields[5]},
+		Subject:   fields[6],
+	}
+
+	for _, line := range strings.Split(fields[7], "\n") {
+		match := trailerRegexp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		identity := Identity{Name: match[2], Email: match[3]}
+		if strings.EqualFold(match[1], "co-authored-by") {
+			commit.CoAuthors = append(commit.CoAuthors, identity)
+		} else {
+			commit.SignedOff = append(commit.SignedOff, identity)
+		}
+	}
+
+	if strings.TrimSpace(fields[8]) != "" {
+		if commit.Note, err = ParseNote(fields[8]); err != nil {
+			commit.Note, commit.NoteError = nil, err
+		}
+	}
+
+	return commit, nil
+}
@@ -0,0 +1,51 @@
+package duet_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	duet "github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/duettest"
+)
+
+func TestReadHistoryWithAnInvalidNote(t *testing.T) {
+	git := duettest.NewGit("/src/project")
+	git.Handlers["git rev-parse"] = func(ctx context.Context, cmd *duet.Command) error {
+		return nil
+	}
+	git.Handlers["git log"] = func(ctx context.Context, cmd *duet.Command) error {
+		fmt.Fprint(cmd.Stderr, "warning: from git\n")
+		fields := []string{
+			"0123456789abcdef0123456789abcdef01234567", "1500000000",
+			"Jane Doe", "jane@awesometown.local",
+			"Jane Doe", "jane@awesometown.local",
+			"Add the thing",
+			"Co-authored-by: Frances Bar <frances@awesometown.local>\n",
+			"participants: [\n",
+		}
+		fmt.Fprintf(cmd.Stdout, "%s\x00", strings.Join(fields, "\x1f"))
+		return nil
+	}
+
+	stderr := new(bytes.Buffer)
+	commits, err := duet.ReadHistory(context.Background(), git, "/src/project", stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := stderr.String(); got != "warning: from git\n" {
+		t.Errorf("got stderr %q, want only the warnings of git", got)
+	}
+	if len(commits) != 1 {
+		t.Fatalf("got %d commits, want 1", len(commits))
+	}
+	if commits[0].Note != nil || commits[0].NoteError == nil {
+		t.Errorf("got note %v and error %v, want the error", commits[0].Note, commits[0].NoteError)
+	}
+	if got := len(commits[0].Participants()); got != 2 {
+		t.Errorf("got %d participants, want the author and co-author", got)
+	}
+}
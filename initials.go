@@ -0,0 +1,234 @@
+package duet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Shortest name fragment resolved to an author, shorter ones are only
+// taken as initials
+const minFragment = 3
+
+// Candidate is an author (or team) something on the command line may refer to
+type Candidate struct {
+	Initials string
+	Name     string
+}
+
+func (c Candidate) String() string {
+	return fmt.Sprintf("%s (%s)", c.Initials, c.Name)
+}
+
+// UnknownInitialsError is returned for initials that match nobody in the
+// authors file, with the closest known initials, teams and names
+type UnknownInitialsError struct {
+	Initials    string
+	Suggestions []Candidate
+}
+
+// Is makes UnknownInitialsError match ErrUnknownInitials
+func (e *UnknownInitialsError) Is(target error) bool {
+	return target == ErrUnknownInitials
+}
+
+func (e *UnknownInitialsError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return fmt.Sprintf("unknown initials %s", e.Initials)
+	}
+	return fmt.Sprintf("unknown initials %s, did you mean %s?", e.Initials, joinCandidates(e.Suggestions))
+}
+
+// AmbiguousInitialsError is returned for a name fragment matching more than
+// one author
+type AmbiguousInitialsError struct {
+	Initials string
+	Matches  []Candidate
+}
+
+// Is makes AmbiguousInitialsError match ErrAmbiguousInitials
+func (e *AmbiguousInitialsError) Is(target error) bool {
+	return target == ErrAmbiguousInitials
+}
+
+func (e *AmbiguousInitialsError) Error() string {
+	return fmt.Sprintf("ambiguous initials %s, could be %s", e.Initials, joinCandidates(e.Matches))
+}
+
+func joinCandidates(candidates []Candidate) string {
+	var s []string
+	for _, c := range candidates {
+		s = append(s, c.String())
+	}
+	return strings.Join(s, ", ")
+}
+
+// Resolve returns the pair with the given initials or, failing that, the only
+// author with a name (or username) starting with the given fragment, or
+// whoever the email lookup finds for the initials (see ByInitials)
+func (a *Pairs) Resolve(initials string) (pair *Pair, err error) {
+	resolved, err := a.resolve(initials)
+	if err != nil {
+		return nil, err
+	}
+	return a.ByInitials(resolved)
+}
+
+func (a *Pairs) resolve(initials string) (string, error) {
+	if _, ok := a.file.Pairs[initials]; ok {
+		return initials, nil
+	}
+
+	var matches []Candidate
+	if len(initials) >= minFragment {
+		fragment := strings.ToLower(initials)
+		for _, c := range a.candidates() {
+			for _, word := range nameWords(a.file.Pairs[c.Initials]) {
+				if strings.HasPrefix(word, fragment) {
+					matches = append(matches, c)
+					break
+				}
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		pair, err := a.lookUpUnknown(initials)
+		if err != nil {
+			return "", err
+		}
+		if pair != nil {
+			return initials, nil
+		}
+		return "", &UnknownInitialsError{Initials: initials, Suggestions: a.suggest(initials)}
+	case 1:
+		return matches[0].Initials, nil
+	default:
+		return "", &AmbiguousInitialsError{Initials: initials, Matches: matches}
+	}
+}
+
+// Candidates returns the authors ordered by initials, with their names from
+// the authors file (without running the email lookup)
+func (a *Pairs) Candidates() []Candidate {
+	return a.candidates()
+}
+
+// candidates returns the authors ordered by initials
+func (a *Pairs) candidates() (candidates []Candidate) {
+	for initials, pair := range a.file.Pairs {
+		name := strings.TrimSpace(strings.SplitN(pair, ";", 2)[0])
+		candidates = append(candidates, Candidate{Initials: initials, Name: name})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Initials < candidates[j].Initials
+	})
+	return candidates
+}
+
+// nameWords returns the lower cased words of an authors file entry, including
+// the username
+func nameWords(pair string) []string {
+	return strings.FieldsFunc(strings.ToLower(pair), func(r rune) bool {
+		return r == ' ' || r == ';' || r == '-' || r == '.'
+	})
+}
+
+// suggest returns the authors and teams closest to the given initials: those
+// within one edit of the initials or team name, or of the start of a name
+func (a *Pairs) suggest(initials string) []Candidate {
+	type scored struct {
+		Candidate
+		distance int
+	}
+
+	var suggestions []scored
+	lower := strings.ToLower(initials)
+	consider := func(c Candidate, targets []string, limit int) {
+		best := -1
+		for _, target := range targets {
+			d := editDistance(lower, target)
+			if d <= limit && (best < 0 || d < best) {
+				best = d
+			}
+		}
+		if best >= 0 {
+			suggestions = append(suggestions, scored{c, best})
+		}
+	}
+
+	for _, c := range a.candidates() {
+		targets := []string{strings.ToLower(c.Initials)}
+		if len(lower) >= minFragment {
+			for _, word := range nameWords(a.file.Pairs[c.Initials]) {
+				if prefix := []rune(word); len(prefix) > len([]rune(lower)) {
+					word = string(prefix[:len([]rune(lower))])
+				}
+				targets = append(targets, word)
+			}
+		}
+		consider(c, targets, 1)
+	}
+
+	var teams []string
+	for team := range a.file.Teams {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
+	for _, team := range teams {
+		consider(Candidate{Initials: team, Name: "team"}, []string{strings.ToLower(team)}, 1)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].distance < suggestions[j].distance
+	})
+
+	var candidates []Candidate
+	for i, s := range suggestions {
+		if i == 3 {
+			break
+		}
+		candidates = append(candidates, s.Candidate)
+	}
+	return candidates
+}
+
+// editDistance returns the number of insertions, deletions, substitutions
+// and transpositions of adjacent characters needed to turn a into b
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(s)][len(t)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
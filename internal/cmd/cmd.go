@@ -3,18 +3,21 @@
 package cmd
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 
 	"github.com/git-duet/git-duet"
 )
 
+// Command runs a git subcommand as the configured pairing, with Runner
+// (duet.ExecRunner if nil)
 type Command struct {
 	Signoff    bool
 	Subcommand string
 	Args       []string
+	Runner     duet.Runner
 }
 
 func New(subcommand string, args ...string) Command {
@@ -40,28 +43,41 @@ func NewWithSignoff(subcommand string, args ...string) Command {
 }
 
 func (duetcmd Command) Execute() error {
-	configuration, err := duet.NewConfiguration()
+	ctx := context.Background()
+	runner := duetcmd.Runner
+	if runner == nil {
+		runner = duet.ExecRunner{}
+	}
+
+	configuration, err := (&duet.Loader{Runner: runner}).Load(ctx)
 	if err != nil {
 		return err
 	}
 
-	var gitConfig *duet.GitConfig
+	gitConfig := &duet.GitConfig{
+		Namespace:     configuration.Namespace,
+		SetUserConfig: configuration.SetGitUserConfig,
+		Runner:        runner,
+	}
 	if configuration.Global {
-		gitConfig = &duet.GitConfig{
-			Namespace:     configuration.Namespace,
-			Scope:         duet.Global,
-			SetUserConfig: configuration.SetGitUserConfig,
-		}
+		gitConfig.Scope = duet.Global
 	} else {
-		gitConfig, err = duet.GetAuthorConfig(configuration.Namespace, configuration.SetGitUserConfig)
+		gitConfig, err = gitConfig.AuthorConfig()
 		if err != nil {
 			return err
 		}
 	}
 
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	if configuration.DriverSource != "" {
+		driver, err := duet.ReadDriver(configuration.DriverSource)
+		if err != nil {
+			return err
+		}
+		if driver != "" {
+			if err = gitConfig.FollowDriver(driver); err != nil {
+				return err
+			}
+		}
 	}
 
 	author, err := gitConfig.GetAuthor()
@@ -70,7 +86,7 @@ func (duetcmd Command) Execute() error {
 	}
 
 	if author == nil {
-		return errors.New("git-author not set")
+		return duet.ErrAuthorNotSet
 	}
 
 	committers, err := gitConfig.GetCommitters()
@@ -78,37 +94,31 @@ func (duetcmd Command) Execute() error {
 		return err
 	}
 
-	var committer *duet.Pair
-	if committers != nil && len(committers) > 0 && duetcmd.Signoff {
-		committer = committers[0]
-		if len(committers) > 1 && configuration.AllowMultipleCommitters {
-			// Reverse the iteration because the committers should sign-off in order
-			for i := len(committers) - 1; i >= 0; i-- {
-				var trailer = "Signed-off-by: " + committers[i].Name + " <" + committers[i].Email + ">"
-				duetcmd.Args = append([]string{trailer}, duetcmd.Args...)
-				duetcmd.Args = append([]string{"--trailer"}, duetcmd.Args...)
-			}
-		} else {
-			duetcmd.Args = append([]string{"--signoff"}, duetcmd.Args...)
-		}
-	} else {
-		committer = author
-	}
-
-	cmd := exec.Command("git", append([]string{duetcmd.Subcommand}, duetcmd.Args...)...)
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	cmd.Env = append(os.Environ(),
-		fmt.Sprintf("GIT_AUTHOR_NAME=%s", author.Name),
-		fmt.Sprintf("GIT_AUTHOR_EMAIL=%s", author.Email),
-		fmt.Sprintf("GIT_COMMITTER_NAME=%s", committer.Name),
-		fmt.Sprintf("GIT_COMMITTER_EMAIL=%s", committer.Email),
-	)
-	err = cmd.Run()
+	identity := configuration.CommitIdentity(author, committers, duetcmd.Signoff)
+
+	args := append(identity.GitArgs, duetcmd.Subcommand)
+	args = append(args, identity.Args...)
+	err = runner.Run(ctx, &duet.Command{
+		Name:   "git",
+		Args:   append(args, duetcmd.Args...),
+		Env:    identity.Env,
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// WarnInvalidNotes reports the commits whose note couldn't be read, which
+// fall back to their headers and trailers
+func WarnInvalidNotes(w io.Writer, commits []*duet.Commit) {
+	for _, commit := range commits {
+		if commit.NoteError != nil {
+			fmt.Fprintf(w, "warning: ignoring invalid note for %s: %v\n", commit.Hash, commit.NoteError)
+		}
+	}
+}
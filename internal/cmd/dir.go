@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pborman/getopt"
+)
+
+// Dir is the directory a command runs in, given with -C <path> options like
+// git -C (each relative to the previous one, empty ones are ignored), empty
+// for the current directory
+// Commands never change to it, it's passed on as the Dir of duet.Loader,
+// duet.GitConfig and the other helpers instead
+type Dir string
+
+// DirOption adds the -C option to set (the command line if nil) and returns
+// the directory it gives
+func DirOption(set *getopt.Set) *Dir {
+	if set == nil {
+		set = getopt.CommandLine
+	}
+	dir := new(Dir)
+	set.Var(dir, 'C', "Run as if started in <path>", "path")
+	return dir
+}
+
+// Set adds the path of a -C option, see getopt.Value
+func (d *Dir) Set(value string, opt getopt.Option) error {
+	if value == "" {
+		return nil
+	}
+
+	dir := d.Path(value)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	*d = Dir(dir)
+	return nil
+}
+
+func (d *Dir) String() string {
+	return string(*d)
+}
+
+// Path returns where a path given on the command line (relative to the
+// directory the command runs in) is
+func (d Dir) Path(path string) string {
+	if d == "" || path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(string(d), path)
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/git-duet/git-duet"
+)
+
+// Hooks are the hooks git-duet-install-hook installs
+var Hooks = []string{"pre-commit", "prepare-commit-msg", "post-commit"}
+
+// InstallHook runs git-duet-install-hook with runner (duet.ExecRunner if nil)
+// for the repository containing dir (the current directory if empty), and its
+// submodules if recurse is set
+func InstallHook(ctx context.Context, runner duet.Runner, dir, hook string, recurse bool) error {
+	if runner == nil {
+		runner = duet.ExecRunner{}
+	}
+
+	args := []string{hook}
+	if recurse {
+		args = []string{"--recurse-submodules", hook}
+	}
+	return runner.Run(ctx, &duet.Command{
+		Name:   "git-duet-install-hook",
+		Args:   args,
+		Dir:    dir,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+}
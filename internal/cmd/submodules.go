@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/git-duet/git-duet"
+)
+
+// PairSubmodules runs pair in the directory of every initialized submodule of
+// the repository containing dir (the current directory if empty), reporting
+// the failures, and returns the exit code (the one of the first failure)
+func PairSubmodules(ctx context.Context, runner duet.Runner, dir string, pair func(dir string) error) int {
+	submodules, err := duet.Submodules(ctx, runner, dir)
+	if err != nil {
+		fmt.Println(err)
+		return duet.ExitCode(err)
+	}
+
+	var failed error
+	for _, submodule := range submodules {
+		if err := pair(submodule.Dir); err != nil {
+			fmt.Printf("%s: %s\n", submodule.Path, err)
+			if failed == nil {
+				failed = err
+			}
+		}
+	}
+	return duet.ExitCode(failed)
+}
+
+// SetSubmodules applies set to the local config of every initialized
+// submodule of the repository of gitConfig, like PairSubmodules
+func SetSubmodules(ctx context.Context, gitConfig *duet.GitConfig, set func(*duet.GitConfig) error) int {
+	return PairSubmodules(ctx, gitConfig.Runner, gitConfig.Dir, func(dir string) error {
+		submoduleConfig := *gitConfig
+		submoduleConfig.Scope = duet.Local
+		submoduleConfig.Dir = dir
+		return set(&submoduleConfig)
+	})
+}
+
+// GitError adds what a failed command printed to its error
+func GitError(err error, output string) error {
+	if output = strings.TrimSpace(output); output != "" {
+		return fmt.Errorf("%s (%w)", strings.Replace(output, "\n", " ", -1), err)
+	}
+	return err
+}
@@ -1,41 +1,105 @@
 package cmdrunner
 
 import (
+	"context"
+
 	"github.com/git-duet/git-duet"
 	"github.com/git-duet/git-duet/internal/cmd"
 )
 
+// Execute runs the commands as the configured pairing, then records the
+// activity, writes the note of the commit made (if enabled) and rotates the
+// author
 func Execute(commands ...cmd.Command) error {
-	configuration, err := duet.NewConfiguration()
+	return ExecuteWith(duet.ExecRunner{}, commands...)
+}
+
+// ExecuteWith is Execute running git with runner, also for the commands
+// without a Runner of their own
+func ExecuteWith(runner duet.Runner, commands ...cmd.Command) error {
+	ctx := context.Background()
+	configuration, err := (&duet.Loader{Runner: runner}).Load(ctx)
 	if err != nil {
 		return err
 	}
 
-	var gitConfig *duet.GitConfig
+	gitConfig := &duet.GitConfig{
+		Namespace:     configuration.Namespace,
+		SetUserConfig: configuration.SetGitUserConfig,
+		Runner:        runner,
+	}
 	if configuration.Global {
-		gitConfig = &duet.GitConfig{
-			Namespace:     configuration.Namespace,
-			Scope:         duet.Global,
-			SetUserConfig: configuration.SetGitUserConfig,
-		}
+		gitConfig.Scope = duet.Global
 	} else {
-		gitConfig, err = duet.GetAuthorConfig(configuration.Namespace, configuration.SetGitUserConfig)
+		gitConfig, err = gitConfig.AuthorConfig()
 		if err != nil {
 			return err
 		}
 	}
 
+	var before string
+	if configuration.Notes {
+		if before, err = duet.Head(ctx, runner, ""); err != nil {
+			return err
+		}
+	}
+
 	for _, command := range commands {
+		if command.Runner == nil {
+			command.Runner = runner
+		}
 		if err := command.Execute(); err != nil {
 			return err
 		}
 	}
 
-	if configuration.RotateAuthor {
-		if err := gitConfig.RotateAuthor(); err != nil {
+	if err := gitConfig.UpdateActivity(); err != nil {
+		return err
+	}
+
+	// the note is for the pairing that made the commit, so before rotating
+	if configuration.Notes {
+		if err := writeNote(ctx, runner, gitConfig, before, len(commands) > 0); err != nil {
+			return err
+		}
+	}
+
+	// a mob timer decides who drives, so don't rotate on our own
+	if configuration.RotateAuthor && configuration.DriverSource == "" {
+		strategy, err := duet.NewRotationStrategy(configuration)
+		if err != nil {
+			return err
+		}
+		if err := gitConfig.RotateWith(strategy); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// writeNote records the pairing in the note of HEAD, unless the commands run
+// didn't commit or it already has one (written by the post-commit hook the
+// commands ran)
+func writeNote(ctx context.Context, runner duet.Runner, gitConfig *duet.GitConfig, before string, ran bool) error {
+	head, err := duet.Head(ctx, runner, "")
+	if err != nil || head == "" || ran && head == before {
+		return err
+	}
+
+	note, err := duet.ReadNote(ctx, runner, "", head)
+	if err != nil || note != nil {
+		return err
+	}
+
+	author, err := gitConfig.GetAuthor()
+	if err != nil || author == nil {
+		return err
+	}
+	committers, err := gitConfig.GetCommitters()
+	if err != nil {
+		return err
+	}
+
+	return duet.WriteNote(ctx, runner, "", head, duet.NewNote(author, committers))
+}
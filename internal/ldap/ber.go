@@ -0,0 +1,246 @@
+// ldap is a minimal LDAPv3 client (simple bind and search), just enough for
+// looking up authors in a directory without depending on an LDAP library
+
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Classes of BER elements
+const (
+	ClassUniversal   = 0x00
+	ClassApplication = 0x40
+	ClassContext     = 0x80
+)
+
+// Universal tags used by LDAP
+const (
+	TagBoolean     = 0x01
+	TagInteger     = 0x02
+	TagOctetString = 0x04
+	TagEnumerated  = 0x0a
+	TagSequence    = 0x10
+	TagSet         = 0x11
+)
+
+// maxPacket bounds the length of the messages read, a directory entry for a
+// person is nowhere near it
+const maxPacket = 1 << 20
+
+// Packet is a BER element, holding Value if primitive and Children if
+// constructed
+type Packet struct {
+	Class       byte
+	Constructed bool
+	Tag         byte
+	Value       []byte
+	Children    []*Packet
+}
+
+// Sequence returns a constructed universal sequence
+func Sequence(children ...*Packet) *Packet {
+	return &Packet{Class: ClassUniversal, Constructed: true, Tag: TagSequence, Children: children}
+}
+
+// Application returns a constructed element of the application class, as LDAP
+// operations are
+func Application(tag byte, children ...*Packet) *Packet {
+	return &Packet{Class: ClassApplication, Constructed: true, Tag: tag, Children: children}
+}
+
+// Context returns a constructed element of the context class
+func Context(tag byte, children ...*Packet) *Packet {
+	return &Packet{Class: ClassContext, Constructed: true, Tag: tag, Children: children}
+}
+
+// ContextValue returns a primitive element of the context class
+func ContextValue(tag byte, value string) *Packet {
+	return &Packet{Class: ClassContext, Tag: tag, Value: []byte(value)}
+}
+
+// OctetString returns a universal octet string
+func OctetString(value string) *Packet {
+	return &Packet{Class: ClassUniversal, Tag: TagOctetString, Value: []byte(value)}
+}
+
+// Integer returns a universal integer
+func Integer(value int64) *Packet {
+	return &Packet{Class: ClassUniversal, Tag: TagInteger, Value: encodeInt(value)}
+}
+
+// Enumerated returns a universal enumerated value
+func Enumerated(value int64) *Packet {
+	return &Packet{Class: ClassUniversal, Tag: TagEnumerated, Value: encodeInt(value)}
+}
+
+// Boolean returns a universal boolean
+func Boolean(value bool) *Packet {
+	p := &Packet{Class: ClassUniversal, Tag: TagBoolean, Value: []byte{0}}
+	if value {
+		p.Value[0] = 0xff
+	}
+	return p
+}
+
+func encodeInt(value int64) []byte {
+	b := []byte{byte(value)}
+	for value > 127 || value < -128 {
+		value >>= 8
+		b = append([]byte{byte(value)}, b...)
+	}
+	return b
+}
+
+// Int returns the value of an integer or enumerated element
+func (p *Packet) Int() (value int64, err error) {
+	if p.Constructed || len(p.Value) == 0 || len(p.Value) > 8 {
+		return 0, errors.New("invalid integer")
+	}
+	value = int64(int8(p.Value[0]))
+	for _, b := range p.Value[1:] {
+		value = value<<8 | int64(b)
+	}
+	return value, nil
+}
+
+// String returns the value of a primitive element as a string
+func (p *Packet) String() string {
+	return string(p.Value)
+}
+
+// Child returns the ith child, nil if there is none
+func (p *Packet) Child(i int) *Packet {
+	if i < 0 || i >= len(p.Children) {
+		return nil
+	}
+	return p.Children[i]
+}
+
+// Bytes encodes the element
+func (p *Packet) Bytes() []byte {
+	content := p.Value
+	if p.Constructed {
+		content = nil
+		for _, child := range p.Children {
+			content = append(content, child.Bytes()...)
+		}
+	}
+
+	identifier := p.Class | p.Tag
+	if p.Constructed {
+		identifier |= 0x20
+	}
+	return append(append([]byte{identifier}, encodeLength(len(content))...), content...)
+}
+
+func encodeLength(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+	var b []byte
+	for ; length > 0; length >>= 8 {
+		b = append([]byte{byte(length)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+// ReadPacket reads the next element from r
+func ReadPacket(r *bufio.Reader) (p *Packet, err error) {
+	identifier, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length, err := readLength(r)
+	if err != nil {
+		return nil, err
+	}
+	if length > maxPacket {
+		return nil, fmt.Errorf("ldap message of %d bytes is too long", length)
+	}
+
+	content := make([]byte, length)
+	if _, err = io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	return decode(identifier, content)
+}
+
+func readLength(r *bufio.Reader) (length int, err error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b < 0x80 {
+		return int(b), nil
+	}
+
+	n := int(b & 0x7f)
+	if n == 0 || n > 4 {
+		return 0, errors.New("unsupported ldap message length")
+	}
+	for i := 0; i < n; i++ {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	return length, nil
+}
+
+func decode(identifier byte, content []byte) (p *Packet, err error) {
+	if identifier&0x1f == 0x1f {
+		return nil, errors.New("unsupported ber tag")
+	}
+
+	p = &Packet{
+		Class:       identifier & 0xc0,
+		Constructed: identifier&0x20 != 0,
+		Tag:         identifier & 0x1f,
+	}
+	if !p.Constructed {
+		p.Value = content
+		return p, nil
+	}
+
+	for len(content) > 0 {
+		if len(content) < 2 {
+			return nil, errors.New("truncated ber element")
+		}
+		childIdentifier := content[0]
+		length, size, err := decodeLength(content[1:])
+		if err != nil {
+			return nil, err
+		}
+		start := 1 + size
+		if length > len(content)-start {
+			return nil, errors.New("truncated ber element")
+		}
+		child, err := decode(childIdentifier, content[start:start+length])
+		if err != nil {
+			return nil, err
+		}
+		p.Children = append(p.Children, child)
+		content = content[start+length:]
+	}
+	return p, nil
+}
+
+// decodeLength returns the length encoded at the start of b and the number
+// of bytes encoding it
+func decodeLength(b []byte) (length, size int, err error) {
+	if b[0] < 0x80 {
+		return int(b[0]), 1, nil
+	}
+	n := int(b[0] & 0x7f)
+	if n == 0 || n > 4 || len(b) < 1+n {
+		return 0, 0, errors.New("unsupported ber length")
+	}
+	for _, c := range b[1 : 1+n] {
+		length = length<<8 | int(c)
+	}
+	return length, 1 + n, nil
+}
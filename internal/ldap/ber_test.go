@@ -0,0 +1,111 @@
+package ldap_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/git-duet/git-duet/internal/ldap"
+)
+
+func readPacket(b []byte) (*ldap.Packet, error) {
+	return ldap.ReadPacket(bufio.NewReader(bytes.NewReader(b)))
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	packet := ldap.Sequence(
+		ldap.Integer(7),
+		ldap.Application(ldap.OpSearchRequest,
+			ldap.OctetString("dc=example,dc=com"),
+			ldap.Enumerated(2),
+			ldap.Boolean(true),
+			ldap.Context(ldap.FilterAnd, ldap.ContextValue(ldap.FilterPresent, "mail")),
+			ldap.OctetString(long),
+		),
+	)
+
+	encoded := packet.Bytes()
+	decoded, err := readPacket(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Bytes(), encoded) {
+		t.Errorf("got %x, want %x", decoded.Bytes(), encoded)
+	}
+
+	op := decoded.Child(1)
+	if op.Class != ldap.ClassApplication || !op.Constructed || op.Tag != ldap.OpSearchRequest {
+		t.Errorf("got class %x, constructed %v and tag %d, want a search request", op.Class, op.Constructed, op.Tag)
+	}
+	if got := op.Child(4).String(); got != long {
+		t.Errorf("got a long string of %d bytes, want %d", len(got), len(long))
+	}
+	if got := op.Child(3).Child(0).String(); got != "mail" {
+		t.Errorf("got %q, want mail", got)
+	}
+	if op.Child(5) != nil {
+		t.Errorf("got a child past the last one")
+	}
+}
+
+func TestPacketInt(t *testing.T) {
+	for _, value := range []int64{0, 1, 127, 128, 255, 256, -1, -128, -129, 1 << 40, -1 << 40} {
+		decoded, err := readPacket(ldap.Integer(value).Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := decoded.Int()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != value {
+			t.Errorf("got %d, want %d", got, value)
+		}
+	}
+
+	if _, err := ldap.Sequence().Int(); err == nil {
+		t.Errorf("a sequence is an integer")
+	}
+}
+
+func TestReadPacketTruncated(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		packet []byte
+		want   string
+	}{
+		{name: "content", packet: []byte{0x30, 0x05, 0x02, 0x01}, want: io.ErrUnexpectedEOF.Error()},
+		{name: "length", packet: []byte{0x30, 0x82, 0x01}, want: io.EOF.Error()},
+		{name: "child", packet: []byte{0x30, 0x03, 0x04, 0x05, 0x61}, want: "truncated ber element"},
+		{name: "child length", packet: []byte{0x30, 0x02, 0x04, 0x82}, want: "unsupported ber length"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := readPacket(test.packet)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("got %v, want %s", err, test.want)
+			}
+		})
+	}
+}
+
+func TestReadPacketOversized(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		packet []byte
+		want   string
+	}{
+		{name: "too long", packet: []byte{0x30, 0x84, 0x7f, 0xff, 0xff, 0xff}, want: "ldap message of 2147483647 bytes is too long"},
+		{name: "length of length", packet: []byte{0x30, 0x85, 0x01, 0x00, 0x00, 0x00, 0x00}, want: "unsupported ldap message length"},
+		{name: "indefinite length", packet: []byte{0x30, 0x80}, want: "unsupported ldap message length"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := readPacket(test.packet)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("got %v, want %s", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Application tags of the LDAP operations used (RFC 4511 4.2)
+const (
+	OpBindRequest       = 0
+	OpBindResponse      = 1
+	OpUnbindRequest     = 2
+	OpSearchRequest     = 3
+	OpSearchResultEntry = 4
+	OpSearchResultDone  = 5
+	OpSearchResultRef   = 19
+)
+
+// Result codes besides success that a search may end with
+const (
+	ResultSuccess           = 0
+	ResultSizeLimitExceeded = 4
+)
+
+// Error is an LDAP operation failing with a result code
+type Error struct {
+	Code    int64
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap result code %d", e.Code)
+	}
+	return fmt.Sprintf("ldap result code %d: %s", e.Code, e.Message)
+}
+
+// Entry is a directory entry found by Search
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Get returns the first value of the attribute (named in any case), empty if
+// the entry doesn't have it
+func (e *Entry) Get(attribute string) string {
+	for name, values := range e.Attributes {
+		if strings.EqualFold(name, attribute) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// Conn is a connection to an LDAP server
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID int64
+}
+
+// Dial connects to the server of an ldap:// or ldaps:// URL, ctx bounding
+// the whole session through the connection's deadline
+func Dial(ctx context.Context, rawurl string) (c *Conn, err error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	host := u.Host
+	switch u.Scheme {
+	case "ldap":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "389")
+		}
+	case "ldaps":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "636")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported ldap url %s (expected ldap:// or ldaps://)", rawurl)
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if u.Scheme == "ldaps" {
+		conn = tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+	}
+
+	return &Conn{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// Close unbinds and closes the connection
+func (c *Conn) Close() error {
+	c.send(Application(OpUnbindRequest))
+	return c.conn.Close()
+}
+
+// Bind authenticates with a simple bind, anonymously if dn is empty
+func (c *Conn) Bind(dn, password string) error {
+	id, err := c.send(Application(OpBindRequest,
+		Integer(3),
+		OctetString(dn),
+		ContextValue(0, password),
+	))
+	if err != nil {
+		return err
+	}
+
+	op, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if op.Tag != OpBindResponse {
+		return fmt.Errorf("unexpected ldap response %d to bind", op.Tag)
+	}
+	return result(op, ResultSuccess)
+}
+
+// Search returns the entries below baseDN matching the filter (see
+// CompileFilter), with the given attributes and at most sizeLimit of them (no
+// limit if zero, as far as the server is concerned)
+func (c *Conn) Search(baseDN, filter string, attributes []string, sizeLimit int) (entries []*Entry, err error) {
+	compiled, err := CompileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var requested []*Packet
+	for _, attribute := range attributes {
+		requested = append(requested, OctetString(attribute))
+	}
+
+	id, err := c.send(Application(OpSearchRequest,
+		OctetString(baseDN),
+		Enumerated(2), // whole subtree
+		Enumerated(0), // never dereference aliases
+		Integer(int64(sizeLimit)),
+		Integer(0),
+		Boolean(false),
+		compiled,
+		Sequence(requested...),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op.Tag {
+		case OpSearchResultEntry:
+			entry, err := parseEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case OpSearchResultRef:
+			// referrals to other servers aren't followed
+		case OpSearchResultDone:
+			return entries, result(op, ResultSuccess, ResultSizeLimitExceeded)
+		default:
+			return nil, fmt.Errorf("unexpected ldap response %d to search", op.Tag)
+		}
+	}
+}
+
+func (c *Conn) send(op *Packet) (id int64, err error) {
+	c.nextID++
+	_, err = c.conn.Write(Sequence(Integer(c.nextID), op).Bytes())
+	return c.nextID, err
+}
+
+// receive reads the next message, returning its operation
+func (c *Conn) receive(id int64) (op *Packet, err error) {
+	message, err := ReadPacket(c.reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(message.Children) < 2 {
+		return nil, fmt.Errorf("invalid ldap message")
+	}
+	if messageID, err := message.Children[0].Int(); err != nil || messageID != id {
+		return nil, fmt.Errorf("unexpected ldap message id")
+	}
+
+	op = message.Children[1]
+	if op.Class != ClassApplication {
+		return nil, fmt.Errorf("invalid ldap message")
+	}
+	return op, nil
+}
+
+// result returns an Error unless the LDAPResult of the operation has one of
+// the expected codes
+func result(op *Packet, expected ...int64) error {
+	if len(op.Children) < 3 {
+		return fmt.Errorf("invalid ldap result")
+	}
+	code, err := op.Children[0].Int()
+	if err != nil {
+		return err
+	}
+	for _, e := range expected {
+		if code == e {
+			return nil
+		}
+	}
+	return &Error{Code: code, Message: op.Children[2].String()}
+}
+
+func parseEntry(op *Packet) (entry *Entry, err error) {
+	if len(op.Children) < 2 {
+		return nil, fmt.Errorf("invalid ldap entry")
+	}
+
+	entry = &Entry{DN: op.Children[0].String(), Attributes: map[string][]string{}}
+	for _, attribute := range op.Children[1].Children {
+		if len(attribute.Children) < 2 {
+			return nil, fmt.Errorf("invalid ldap attribute in %s", entry.DN)
+		}
+		name := attribute.Children[0].String()
+		for _, value := range attribute.Children[1].Children {
+			entry.Attributes[name] = append(entry.Attributes[name], value.String())
+		}
+	}
+	return entry, nil
+}
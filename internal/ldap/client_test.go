@@ -0,0 +1,149 @@
+package ldap_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/git-duet/git-duet/internal/ldap"
+)
+
+// serve answers the operations of every connection to a local port with what
+// handle returns (nothing leaves the operation unanswered), returning the
+// ldap:// URL of the port
+func serve(t *testing.T, handle func(op *ldap.Packet) []*ldap.Packet) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					message, err := ldap.ReadPacket(reader)
+					if err != nil {
+						return
+					}
+					for _, response := range handle(message.Child(1)) {
+						if _, err = conn.Write(ldap.Sequence(message.Child(0), response).Bytes()); err != nil {
+							return
+						}
+					}
+				}
+			}()
+		}
+	}()
+
+	return "ldap://" + listener.Addr().String()
+}
+
+func result(tag byte, code int64, message string) *ldap.Packet {
+	return ldap.Application(tag, ldap.Enumerated(code), ldap.OctetString(""), ldap.OctetString(message))
+}
+
+func attribute(name string, values ...string) *ldap.Packet {
+	var set []*ldap.Packet
+	for _, value := range values {
+		set = append(set, ldap.OctetString(value))
+	}
+	return ldap.Sequence(ldap.OctetString(name), &ldap.Packet{Class: ldap.ClassUniversal, Constructed: true, Tag: ldap.TagSet, Children: set})
+}
+
+func dial(t *testing.T, url string, timeout time.Duration) *ldap.Conn {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	t.Cleanup(cancel)
+
+	conn, err := ldap.Dial(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestSearch(t *testing.T) {
+	var baseDN, filter string
+	url := serve(t, func(op *ldap.Packet) []*ldap.Packet {
+		switch op.Tag {
+		case ldap.OpBindRequest:
+			return []*ldap.Packet{result(ldap.OpBindResponse, ldap.ResultSuccess, "")}
+		case ldap.OpSearchRequest:
+			baseDN = op.Child(0).String()
+			filter = op.Child(6).Child(1).String()
+			return []*ldap.Packet{
+				ldap.Application(ldap.OpSearchResultEntry,
+					ldap.OctetString("uid=jane,dc=example,dc=com"),
+					ldap.Sequence(attribute("mail", "jane@example.com", "jd@example.com"), attribute("cn", "Jane Doe")),
+				),
+				ldap.Application(ldap.OpSearchResultRef, ldap.OctetString("ldap://elsewhere.example.com")),
+				result(ldap.OpSearchResultDone, ldap.ResultSizeLimitExceeded, ""),
+			}
+		}
+		return nil
+	})
+
+	conn := dial(t, url, 5*time.Second)
+	if err := conn.Bind("cn=git-duet,dc=example,dc=com", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := conn.Search("dc=example,dc=com", "(uid=jane)", []string{"mail", "cn"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if baseDN != "dc=example,dc=com" || filter != "jane" {
+		t.Errorf("got a search of %q for %q, want dc=example,dc=com for jane", baseDN, filter)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if got := entries[0].Get("MAIL"); got != "jane@example.com" {
+		t.Errorf("got mail %q, want jane@example.com", got)
+	}
+	if got := entries[0].Get("uid"); got != "" {
+		t.Errorf("got uid %q, which wasn't returned", got)
+	}
+}
+
+func TestBindFails(t *testing.T) {
+	url := serve(t, func(op *ldap.Packet) []*ldap.Packet {
+		return []*ldap.Packet{result(ldap.OpBindResponse, 49, "invalid credentials")}
+	})
+
+	err := dial(t, url, 5*time.Second).Bind("cn=git-duet,dc=example,dc=com", "wrong")
+	var ldapErr *ldap.Error
+	if !errors.As(err, &ldapErr) || ldapErr.Code != 49 {
+		t.Errorf("got %v, want result code 49", err)
+	}
+	if err != nil && err.Error() != "ldap result code 49: invalid credentials" {
+		t.Errorf("got %q", err)
+	}
+}
+
+func TestSearchTimesOut(t *testing.T) {
+	url := serve(t, func(op *ldap.Packet) []*ldap.Packet { return nil })
+
+	_, err := dial(t, url, 100*time.Millisecond).Search("dc=example,dc=com", "(uid=jane)", nil, 0)
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("got %v, want a timeout", err)
+	}
+}
+
+func TestDialRejectsOtherSchemes(t *testing.T) {
+	_, err := ldap.Dial(context.Background(), "http://ldap.example.com")
+	if err == nil || err.Error() != "unsupported ldap url http://ldap.example.com (expected ldap:// or ldaps://)" {
+		t.Errorf("got %v", err)
+	}
+}
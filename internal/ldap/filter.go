@@ -0,0 +1,182 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Context tags of the filter choices (RFC 4511 4.5.1)
+const (
+	FilterAnd            = 0
+	FilterOr             = 1
+	FilterNot            = 2
+	FilterEquality       = 3
+	FilterSubstrings     = 4
+	FilterGreaterOrEqual = 5
+	FilterLessOrEqual    = 6
+	FilterPresent        = 7
+	FilterApprox         = 8
+)
+
+// Context tags of the parts of a substrings filter
+const (
+	SubstringInitial = 0
+	SubstringAny     = 1
+	SubstringFinal   = 2
+)
+
+// EscapeFilter escapes a value for a filter string, so that names with
+// parentheses or asterisks match literally
+func EscapeFilter(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\', '*', '(', ')', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// CompileFilter encodes a filter string (RFC 4515), e.g. "(&(uid=jd)(mail=*))"
+// Extensible matches aren't supported
+func CompileFilter(filter string) (p *Packet, err error) {
+	filter = strings.TrimSpace(filter)
+	if !strings.HasPrefix(filter, "(") {
+		filter = "(" + filter + ")"
+	}
+
+	p, rest, err := compileFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ldap filter %s: %v", filter, err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("invalid ldap filter %s: unexpected %s", filter, rest)
+	}
+	return p, nil
+}
+
+// compileFilter encodes the parenthesized filter at the start of s, returning
+// what follows it
+func compileFilter(s string) (p *Packet, rest string, err error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, "", fmt.Errorf("expected ( at %s", s)
+	}
+	s = s[1:]
+
+	switch {
+	case strings.HasPrefix(s, "&"), strings.HasPrefix(s, "|"):
+		tag := byte(FilterAnd)
+		if s[0] == '|' {
+			tag = FilterOr
+		}
+		p = Context(tag)
+		for s = s[1:]; strings.HasPrefix(s, "("); {
+			var child *Packet
+			if child, s, err = compileFilter(s); err != nil {
+				return nil, "", err
+			}
+			p.Children = append(p.Children, child)
+		}
+	case strings.HasPrefix(s, "!"):
+		var child *Packet
+		if child, s, err = compileFilter(s[1:]); err != nil {
+			return nil, "", err
+		}
+		p = Context(FilterNot, child)
+	default:
+		end := strings.IndexByte(s, ')')
+		if end < 0 {
+			return nil, "", fmt.Errorf("missing )")
+		}
+		if p, err = compileItem(s[:end]); err != nil {
+			return nil, "", err
+		}
+		s = s[end:]
+	}
+
+	if !strings.HasPrefix(s, ")") {
+		return nil, "", fmt.Errorf("missing )")
+	}
+	return p, s[1:], nil
+}
+
+func compileItem(item string) (p *Packet, err error) {
+	eq := strings.IndexByte(item, '=')
+	if eq < 1 {
+		return nil, fmt.Errorf("expected attribute=value in %s", item)
+	}
+	attribute, value := item[:eq], item[eq+1:]
+
+	tag := byte(FilterEquality)
+	switch attribute[len(attribute)-1] {
+	case '>':
+		tag = FilterGreaterOrEqual
+	case '<':
+		tag = FilterLessOrEqual
+	case '~':
+		tag = FilterApprox
+	}
+	if tag != FilterEquality {
+		attribute = attribute[:len(attribute)-1]
+	}
+	if attribute == "" || strings.ContainsAny(attribute, "()&|!*\\ ") {
+		return nil, fmt.Errorf("invalid attribute in %s", item)
+	}
+
+	if tag == FilterEquality && value == "*" {
+		return ContextValue(FilterPresent, attribute), nil
+	}
+
+	if tag == FilterEquality && strings.Contains(value, "*") {
+		parts := strings.Split(value, "*")
+		substrings := Sequence()
+		for i, part := range parts {
+			if part == "" {
+				continue
+			}
+			unescaped, err := unescapeFilter(part)
+			if err != nil {
+				return nil, err
+			}
+			partTag := byte(SubstringAny)
+			if i == 0 {
+				partTag = SubstringInitial
+			} else if i == len(parts)-1 {
+				partTag = SubstringFinal
+			}
+			substrings.Children = append(substrings.Children, ContextValue(partTag, unescaped))
+		}
+		return Context(FilterSubstrings, OctetString(attribute), substrings), nil
+	}
+
+	unescaped, err := unescapeFilter(value)
+	if err != nil {
+		return nil, err
+	}
+	return Context(tag, OctetString(attribute), OctetString(unescaped)), nil
+}
+
+// unescapeFilter decodes the \XX escapes of a filter value
+func unescapeFilter(value string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			b.WriteByte(value[i])
+			continue
+		}
+		if i+3 > len(value) {
+			return "", fmt.Errorf("invalid escape in %s", value)
+		}
+		decoded, err := hex.DecodeString(value[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %s", value)
+		}
+		b.Write(decoded)
+		i += 2
+	}
+	return b.String(), nil
+}
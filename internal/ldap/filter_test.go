@@ -0,0 +1,113 @@
+package ldap_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/git-duet/git-duet/internal/ldap"
+)
+
+func equality(attribute, value string) *ldap.Packet {
+	return ldap.Context(ldap.FilterEquality, ldap.OctetString(attribute), ldap.OctetString(value))
+}
+
+func TestCompileFilter(t *testing.T) {
+	for _, test := range []struct {
+		filter string
+		want   *ldap.Packet
+	}{
+		{filter: "uid=jd", want: equality("uid", "jd")},
+		{filter: " (uid=jd) ", want: equality("uid", "jd")},
+		{filter: "(mail=*)", want: ldap.ContextValue(ldap.FilterPresent, "mail")},
+		{filter: "(uidNumber>=1000)", want: ldap.Context(ldap.FilterGreaterOrEqual, ldap.OctetString("uidNumber"), ldap.OctetString("1000"))},
+		{filter: "(uidNumber<=1000)", want: ldap.Context(ldap.FilterLessOrEqual, ldap.OctetString("uidNumber"), ldap.OctetString("1000"))},
+		{filter: "(cn~=jane)", want: ldap.Context(ldap.FilterApprox, ldap.OctetString("cn"), ldap.OctetString("jane"))},
+		{
+			filter: "(cn=Ja*D*e)",
+			want: ldap.Context(ldap.FilterSubstrings, ldap.OctetString("cn"), ldap.Sequence(
+				ldap.ContextValue(ldap.SubstringInitial, "Ja"),
+				ldap.ContextValue(ldap.SubstringAny, "D"),
+				ldap.ContextValue(ldap.SubstringFinal, "e"),
+			)),
+		},
+		{
+			filter: "(cn=*Doe)",
+			want: ldap.Context(ldap.FilterSubstrings, ldap.OctetString("cn"), ldap.Sequence(
+				ldap.ContextValue(ldap.SubstringFinal, "Doe"),
+			)),
+		},
+		{filter: `(cn=a\2a)`, want: equality("cn", "a*")},
+		{filter: `(cn=\28jd\29 \5c)`, want: equality("cn", `(jd) \`)},
+		{
+			filter: "(&(objectClass=person)(|(uid=jd)(!(mail=*))))",
+			want: ldap.Context(ldap.FilterAnd,
+				equality("objectClass", "person"),
+				ldap.Context(ldap.FilterOr,
+					equality("uid", "jd"),
+					ldap.Context(ldap.FilterNot, ldap.ContextValue(ldap.FilterPresent, "mail")),
+				),
+			),
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			got, err := ldap.CompileFilter(test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), test.want.Bytes()) {
+				t.Errorf("got %x, want %x", got.Bytes(), test.want.Bytes())
+			}
+		})
+	}
+}
+
+func TestCompileFilterErrors(t *testing.T) {
+	for _, test := range []struct {
+		filter string
+		want   string
+	}{
+		{filter: "(uid=jd", want: "invalid ldap filter (uid=jd: missing )"},
+		{filter: "(&(uid=jd)", want: "invalid ldap filter (&(uid=jd): missing )"},
+		{filter: "(uid=jd))", want: "invalid ldap filter (uid=jd)): unexpected )"},
+		{filter: "(=jd)", want: "invalid ldap filter (=jd): expected attribute=value in =jd"},
+		{filter: "(u id=jd)", want: "invalid ldap filter (u id=jd): invalid attribute in u id=jd"},
+		{filter: `(uid=\zz)`, want: `invalid ldap filter (uid=\zz): invalid escape in \zz`},
+		{filter: `(uid=jd\2)`, want: `invalid ldap filter (uid=jd\2): invalid escape in jd\2`},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			_, err := ldap.CompileFilter(test.filter)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("got %v, want %s", err, test.want)
+			}
+		})
+	}
+}
+
+func TestEscapeFilter(t *testing.T) {
+	for _, test := range []struct {
+		value string
+		want  string
+	}{
+		{value: "Jane Doe", want: "Jane Doe"},
+		{value: "*", want: `\2a`},
+		{value: `\2a`, want: `\5c2a`},
+		{value: "Jane (JD) Doe*", want: `Jane \28JD\29 Doe\2a`},
+		{value: "nul\x00", want: `nul\00`},
+	} {
+		t.Run(test.value, func(t *testing.T) {
+			escaped := ldap.EscapeFilter(test.value)
+			if escaped != test.want {
+				t.Errorf("got %q, want %q", escaped, test.want)
+			}
+
+			// the escaped value matches literally, never as a wildcard
+			got, err := ldap.CompileFilter("(cn=" + escaped + ")")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := equality("cn", test.value); !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Errorf("got %x, want %x", got.Bytes(), want.Bytes())
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package duet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/git-duet/git-duet/internal/ldap"
+)
+
+// LDAPConfig is the `ldap` section of the authors file, looking authors up in
+// a directory
+// Filter is a template (like `email_template`) of the search filter, e.g.
+// "(uid={{.Username}})", with the values escaped for it. The bind password is
+// read from the environment variable named by BindPasswordEnv, keeping it out
+// of the authors file; the bind is anonymous without BindDN
+type LDAPConfig struct {
+	URL             string         `yaml:"url"`
+	BaseDN          string         `yaml:"base_dn"`
+	BindDN          string         `yaml:"bind_dn,omitempty"`
+	BindPasswordEnv string         `yaml:"bind_password_env,omitempty"`
+	Filter          string         `yaml:"filter"`
+	Attributes      LDAPAttributes `yaml:"attributes,omitempty"`
+}
+
+// LDAPAttributes maps the directory attributes onto the fields of a pair,
+// only the email (mail by default) is taken unless the others are mapped
+type LDAPAttributes struct {
+	Email      string `yaml:"email,omitempty"`
+	Name       string `yaml:"name,omitempty"`
+	Username   string `yaml:"username,omitempty"`
+	SigningKey string `yaml:"signing_key,omitempty"`
+}
+
+func (c *LDAPConfig) validate() error {
+	if c.URL == "" || c.Filter == "" {
+		return errors.New("ldap needs a url and a filter")
+	}
+	_, err := template.New("filter").Funcs(templateFuncs).Parse(c.Filter)
+	return err
+}
+
+func (c *LDAPConfig) emailAttribute() string {
+	if c.Attributes.Email == "" {
+		return "mail"
+	}
+	return c.Attributes.Email
+}
+
+// resolvesUnknown returns whether the directory is searched for initials
+// missing from the authors file, which takes a name to be mapped
+func (c *LDAPConfig) resolvesUnknown() bool {
+	return c != nil && c.Attributes.Name != ""
+}
+
+// defaultLDAPTimeout bounds searches when the lookup has no timeout, unlike a
+// lookup executable an unreachable directory would otherwise hang until the
+// connection times out
+const defaultLDAPTimeout = 5 * time.Second
+
+// Lookup searches the directory for the pair, through the lookup's cache and
+// with its timeout (defaultLDAPTimeout if none), returning an empty response
+// if nobody matches
+func (c *LDAPConfig) Lookup(ctx context.Context, lookup EmailLookup, pair *Pair) (response *LookupResponse, err error) {
+	filter, err := c.filter(pair)
+	if err != nil {
+		return nil, err
+	}
+
+	key := lookupKey(fmt.Sprintf("ldap\x00%s\x00%s\x00%s\x00%+v", c.URL, c.BaseDN, filter, c.Attributes), pair)
+	return lookup.Cache.fetch(key, func() (*LookupResponse, error) {
+		timeout := lookup.Timeout
+		if timeout <= 0 {
+			timeout = defaultLDAPTimeout
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		response, err := c.search(ctx, filter)
+		var netErr net.Error
+		if (errors.As(err, &netErr) && netErr.Timeout()) || ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("ldap lookup for %s timed out after %s", pair.Initials, timeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ldap lookup for %s failed: %v", pair.Initials, err)
+		}
+		return response, nil
+	})
+}
+
+func (c *LDAPConfig) filter(pair *Pair) (string, error) {
+	t, err := template.New("filter").Funcs(templateFuncs).Parse(c.Filter)
+	if err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	escaped := Pair{
+		Initials: ldap.EscapeFilter(pair.Initials),
+		Name:     ldap.EscapeFilter(pair.Name),
+		Username: ldap.EscapeFilter(pair.Username),
+	}
+	if err = t.Execute(&out, escaped); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func (c *LDAPConfig) search(ctx context.Context, filter string) (response *LookupResponse, err error) {
+	conn, err := ldap.Dial(ctx, c.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if c.BindDN != "" {
+		password := ""
+		if c.BindPasswordEnv != "" {
+			password = os.Getenv(c.BindPasswordEnv)
+		}
+		if err = conn.Bind(c.BindDN, password); err != nil {
+			return nil, err
+		}
+	}
+
+	attributes := []string{c.emailAttribute()}
+	for _, attribute := range []string{c.Attributes.Name, c.Attributes.Username, c.Attributes.SigningKey} {
+		if attribute != "" {
+			attributes = append(attributes, attribute)
+		}
+	}
+
+	entries, err := conn.Search(c.BaseDN, filter, attributes, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(entries) {
+	case 0:
+		return &LookupResponse{}, nil
+	case 1:
+	default:
+		var dns []string
+		for _, entry := range entries {
+			dns = append(dns, entry.DN)
+		}
+		return nil, fmt.Errorf("%s matches more than one entry (%s)", filter, strings.Join(dns, ", "))
+	}
+
+	entry := entries[0]
+	response = &LookupResponse{Email: entry.Get(c.emailAttribute())}
+	if c.Attributes.Name != "" {
+		response.Name = entry.Get(c.Attributes.Name)
+	}
+	if c.Attributes.Username != "" {
+		response.Username = entry.Get(c.Attributes.Username)
+	}
+	if c.Attributes.SigningKey != "" {
+		response.SigningKey = entry.Get(c.Attributes.SigningKey)
+	}
+	return response, nil
+}
@@ -0,0 +1,116 @@
+package duet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Email lookup protocols
+// LookupProtocolArgs passes the initials, name and username as arguments and
+// takes the email from the output
+// LookupProtocolJSON writes a LookupRequest to the command's input and reads a
+// LookupResponse from its output
+const (
+	LookupProtocolArgs = 1
+	LookupProtocolJSON = 2
+)
+
+// EmailLookup is the external command determining the email (and, with the
+// JSON protocol, the name) of pairs
+// Timeout kills the command once exceeded (no limit if zero), Fallback
+// builds the email from the authors file when the command fails instead of
+// returning its error and Cache keeps the responses (see LookupCache)
+type EmailLookup struct {
+	Command  string
+	Protocol int
+	Timeout  time.Duration
+	Fallback bool
+	Cache    LookupCache
+}
+
+// LookupRequest is written to the email lookup command with the JSON protocol
+// Name and Username are empty for initials missing from the authors file
+type LookupRequest struct {
+	Initials string `json:"initials"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+}
+
+// LookupResponse is read from the email lookup command with the JSON protocol
+// Empty fields leave the ones from the authors file, an empty Email builds it
+// from the authors file as well
+type LookupResponse struct {
+	Email      string `json:"email"`
+	Name       string `json:"name,omitempty"`
+	Username   string `json:"username,omitempty"`
+	SigningKey string `json:"signing_key,omitempty"`
+}
+
+// Lookup returns the cached response for the pair or runs the command,
+// returning an empty response if the command isn't set
+func (l EmailLookup) Lookup(ctx context.Context, runner Runner, pair *Pair) (response *LookupResponse, err error) {
+	if l.Command == "" {
+		return &LookupResponse{}, nil
+	}
+
+	key := lookupKey(fmt.Sprintf("%s\x00%d", l.Command, l.Protocol), pair)
+	return l.Cache.fetch(key, func() (*LookupResponse, error) {
+		return l.run(ctx, runner, pair)
+	})
+}
+
+func (l EmailLookup) run(ctx context.Context, runner Runner, pair *Pair) (response *LookupResponse, err error) {
+	response = &LookupResponse{}
+
+	if l.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.Timeout)
+		defer cancel()
+	}
+
+	output := new(bytes.Buffer)
+	cmd := &Command{Name: l.Command, Stdout: output}
+	switch l.Protocol {
+	case 0, LookupProtocolArgs:
+		cmd.Args = []string{pair.Initials, pair.Name, pair.Username}
+	case LookupProtocolJSON:
+		request, err := json.Marshal(LookupRequest{Initials: pair.Initials, Name: pair.Name, Username: pair.Username})
+		if err != nil {
+			return nil, err
+		}
+		cmd.Stdin = bytes.NewReader(request)
+	default:
+		return nil, fmt.Errorf("unknown email lookup protocol %d", l.Protocol)
+	}
+
+	err = run(ctx, runner, cmd)
+	if l.Timeout > 0 && ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("email lookup for %s timed out after %s", pair.Initials, l.Timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if l.Protocol != LookupProtocolJSON {
+		response.Email = strings.TrimSpace(output.String())
+		return response, nil
+	}
+
+	if len(bytes.TrimSpace(output.Bytes())) == 0 {
+		return response, nil
+	}
+	if err = json.Unmarshal(output.Bytes(), response); err != nil {
+		return nil, fmt.Errorf("invalid email lookup response for %s: %v", pair.Initials, err)
+	}
+	return response, nil
+}
+
+// resolvesUnknown returns whether the lookup is asked about initials missing
+// from the authors file, which takes the JSON protocol to return a name
+func (l EmailLookup) resolvesUnknown() bool {
+	return l.Command != "" && l.Protocol == LookupProtocolJSON
+}
@@ -0,0 +1,149 @@
+package duet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Directories of the lookup cache: responses with an email, and lookups
+// that returned none (cached for NegativeTTL)
+const (
+	cacheHits   = "lookup/hits"
+	cacheMisses = "lookup/misses"
+)
+
+// LookupCache keeps email lookup responses on disk, keyed by the command and
+// the initials, name and username looked up
+// Responses with an email are kept for TTL, empty ones (falling back to the
+// authors file) for NegativeTTL, nothing is cached with a zero TTL or an empty
+// Dir. Refresh ignores the cached responses, replacing them with fresh ones
+// Failing lookups are never cached
+type LookupCache struct {
+	Dir         string
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	Refresh     bool
+}
+
+// DefaultCacheDir returns the git-duet directory of $XDG_CACHE_HOME, or of
+// ~/.cache if unset, with getenv looking up environment variables
+func DefaultCacheDir(getenv func(key string) string) string {
+	if dir := getenv("XDG_CACHE_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "git-duet")
+	}
+	if home := getenv("HOME"); home != "" {
+		return filepath.Join(home, ".cache", "git-duet")
+	}
+	return ""
+}
+
+// ClearCache removes everything cached in dir
+func ClearCache(dir string) error {
+	return os.RemoveAll(dir)
+}
+
+// lookupKey identifies the lookup of the pair from source, e.g. the command
+// and protocol
+func lookupKey(source string, pair *Pair) string {
+	hash := sha256.New()
+	for _, field := range []string{source, pair.Initials, pair.Name, pair.Username} {
+		hash.Write([]byte(field))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// fetch returns the cached response for key or the one from lookup, caching
+// it
+func (c LookupCache) fetch(key string, lookup func() (*LookupResponse, error)) (response *LookupResponse, err error) {
+	if response, ok := c.get(key); ok {
+		return response, nil
+	}
+	if response, err = lookup(); err != nil {
+		return nil, err
+	}
+	c.put(key, response)
+	return response, nil
+}
+
+// get returns the cached response for key, false if there is none or it has
+// expired
+func (c LookupCache) get(key string) (response *LookupResponse, ok bool) {
+	if c.Dir == "" || c.Refresh {
+		return nil, false
+	}
+
+	if contents, ok := c.read(filepath.Join(c.Dir, cacheHits, key), c.TTL); ok {
+		response = &LookupResponse{}
+		if err := json.Unmarshal(contents, response); err == nil && response.Email != "" {
+			return response, true
+		}
+	}
+	if _, ok := c.read(filepath.Join(c.Dir, cacheMisses, key), c.NegativeTTL); ok {
+		return &LookupResponse{}, true
+	}
+	return nil, false
+}
+
+func (c LookupCache) read(filename string, ttl time.Duration) (contents []byte, ok bool) {
+	if ttl <= 0 {
+		return nil, false
+	}
+	info, err := os.Stat(filename)
+	if err != nil || time.Since(info.ModTime()) >= ttl {
+		return nil, false
+	}
+	contents, err = ioutil.ReadFile(filename)
+	return contents, err == nil
+}
+
+// put caches the response for key, a cache that can't be written is skipped
+// as the lookup can simply run again
+func (c LookupCache) put(key string, response *LookupResponse) {
+	if c.Dir == "" {
+		return
+	}
+
+	hit, miss := filepath.Join(c.Dir, cacheHits, key), filepath.Join(c.Dir, cacheMisses, key)
+	os.Remove(hit)
+	os.Remove(miss)
+
+	if response.Email == "" {
+		if c.NegativeTTL > 0 {
+			writeCacheFile(miss, []byte("{}\n"))
+		}
+		return
+	}
+	if c.TTL > 0 {
+		if contents, err := json.Marshal(response); err == nil {
+			writeCacheFile(hit, contents)
+		}
+	}
+}
+
+// writeCacheFile replaces the file through a temporary one, so that concurrent
+// lookups never read a partial response
+func writeCacheFile(filename string, contents []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(contents); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
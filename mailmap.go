@@ -0,0 +1,62 @@
+package duet
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// MailmapEntry is a line of a .mailmap file mapping the identity found in
+// commits to the proper one (see gitmailmap(5))
+// Commit.Name is empty if the entry matches on email only
+type MailmapEntry struct {
+	Proper Identity
+	Commit Identity
+}
+
+var mailmapRegexp = regexp.MustCompile(`^([^<]*)<([^>]*)>\s*(?:([^<]*)<([^>]*)>)?`)
+
+// ParseMailmap reads the entries from a .mailmap file, ignoring comments and
+// blank lines
+func ParseMailmap(r io.Reader) (entries []MailmapEntry, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		match := mailmapRegexp.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+
+		entry := MailmapEntry{Proper: Identity{Name: strings.TrimSpace(match[1]), Email: match[2]}}
+		if match[4] != "" {
+			entry.Commit = Identity{Name: strings.TrimSpace(match[3]), Email: match[4]}
+		} else {
+			// a single email replaces the name for that email
+			entry.Commit = Identity{Email: match[2]}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+func (e MailmapEntry) String() string {
+	line := "<" + e.Proper.Email + ">"
+	if e.Proper.Name != "" {
+		line = e.Proper.Name + " " + line
+	}
+
+	if e.Commit.Email == e.Proper.Email && e.Commit.Name == "" {
+		return line
+	}
+
+	if e.Commit.Name != "" {
+		return line + " " + e.Commit.String()
+	}
+	return line + " <" + e.Commit.Email + ">"
+}
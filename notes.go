@@ -0,0 +1,103 @@
+package duet
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// NotesRef is the ref of the git notes recording who took part in commits
+const NotesRef = "refs/notes/duet"
+
+// Note records everybody who took part in a commit, unlike the author and
+// committer headers it holds any number of people, and unlike trailers it can
+// be written (or fixed) after the fact without rewriting history
+// Participants are ordered by rotation position
+type Note struct {
+	Participants []NoteParticipant `yaml:"participants"`
+}
+
+// NoteParticipant is a person taking part in a commit
+// Position is the place in the rotation: 0 for the author, then the
+// committers in the order they were set (the next author first)
+type NoteParticipant struct {
+	Initials string `yaml:"initials,omitempty"`
+	Name     string `yaml:"name"`
+	Email    string `yaml:"email"`
+	Position int    `yaml:"position"`
+}
+
+// NewNote returns the note for a commit by the author and committers
+func NewNote(author *Pair, committers []*Pair) *Note {
+	note := &Note{}
+	for i, pair := range append([]*Pair{author}, committers...) {
+		note.Participants = append(note.Participants, NoteParticipant{
+			Initials: pair.Initials,
+			Name:     pair.Name,
+			Email:    pair.Email,
+			Position: i,
+		})
+	}
+	return note
+}
+
+// ParseNote parses the text of a note, returning an error if it lists nobody
+func ParseNote(text string) (note *Note, err error) {
+	note = &Note{}
+	if err = yaml.Unmarshal([]byte(text), note); err != nil {
+		return nil, err
+	}
+	if len(note.Participants) == 0 {
+		return nil, fmt.Errorf("no participants in note")
+	}
+	for _, p := range note.Participants {
+		if p.Email == "" {
+			return nil, fmt.Errorf("no email for participant %s in note", p.Name)
+		}
+	}
+	return note, nil
+}
+
+// Identities returns the name and email of the participants
+func (n *Note) Identities() (identities []Identity) {
+	for _, p := range n.Participants {
+		identities = append(identities, Identity{Name: p.Name, Email: p.Email})
+	}
+	return identities
+}
+
+// ReadNote returns the note of commit in the repository containing dir (the
+// current directory if empty), nil if it has none
+func ReadNote(ctx context.Context, runner Runner, dir, commit string) (note *Note, err error) {
+	output := new(bytes.Buffer)
+	err = run(ctx, runner, &Command{
+		Name:   "git",
+		Args:   []string{"notes", "--ref", NotesRef, "show", commit},
+		Dir:    dir,
+		Stdout: output,
+	})
+	if code, ok := exitStatus(err); ok && code == 1 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseNote(output.String())
+}
+
+// WriteNote sets the note of commit in the repository containing dir (the
+// current directory if empty), replacing the one it has
+func WriteNote(ctx context.Context, runner Runner, dir, commit string, note *Note) error {
+	text, err := yaml.Marshal(note)
+	if err != nil {
+		return err
+	}
+	return run(ctx, runner, &Command{
+		Name:  "git",
+		Args:  []string{"notes", "--ref", NotesRef, "add", "--force", "--file", "-", commit},
+		Dir:   dir,
+		Stdin: bytes.NewReader(text),
+	})
+}
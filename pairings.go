@@ -0,0 +1,138 @@
+package duet
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Pairing summarizes the commits two people made together
+// People holds the initials of both (or the email if the person is not in the
+// authors file) in sorted order
+// Last is the zero Time if they never paired
+type Pairing struct {
+	People  [2]string
+	Commits int
+	Last    time.Time
+}
+
+// Participant is somebody who took part in a commit, with the name and email
+// from the authors file if they are in it (Initials is empty otherwise)
+type Participant struct {
+	Identity
+	Initials string
+}
+
+// Person returns the initials of the participant, or the lower-cased email if
+// they aren't in the authors file
+func (p Participant) Person() string {
+	if p.Initials != "" {
+		return p.Initials
+	}
+	return strings.ToLower(p.Email)
+}
+
+// Participants returns everybody who took part in commit, resolved through
+// the authors file, without duplicates
+// The participants listed in the note of the commit, if it has one, take
+// precedence over its headers and trailers
+func (a *Pairs) Participants(commit *Commit) (participants []Participant, err error) {
+	identities := commit.Participants()
+	if commit.Note != nil {
+		identities = commit.Note.Identities()
+	}
+
+	seen := map[string]bool{}
+	for _, identity := range identities {
+		pair, err := a.Identify(identity)
+		if err != nil {
+			return nil, err
+		}
+
+		participant := Participant{Identity: identity}
+		if pair != nil {
+			participant = Participant{Identity: Identity{Name: pair.Name, Email: pair.Email}, Initials: pair.Initials}
+		}
+		if !seen[participant.Person()] {
+			seen[participant.Person()] = true
+			participants = append(participants, participant)
+		}
+	}
+	return participants, nil
+}
+
+// People returns everybody who took part in commit (see Participant.Person)
+func (a *Pairs) People(commit *Commit) (people []string, err error) {
+	participants, err := a.Participants(commit)
+	if err != nil {
+		return nil, err
+	}
+	for _, participant := range participants {
+		people = append(people, participant.Person())
+	}
+	return people, nil
+}
+
+// Pairings tallies who paired with whom in commits
+// If all is set, pairs from the authors file who never paired are included
+// The result is ordered from least to most recently paired
+func (a *Pairs) Pairings(commits []*Commit, all bool) (pairings []*Pairing, err error) {
+	byPeople := map[[2]string]*Pairing{}
+
+	if all {
+		everyone, err := a.All()
+		if err != nil {
+			return nil, err
+		}
+		for i := range everyone {
+			for j := i + 1; j < len(everyone); j++ {
+				people := pairKey(everyone[i].Initials, everyone[j].Initials)
+				byPeople[people] = &Pairing{People: people}
+			}
+		}
+	}
+
+	for _, commit := range commits {
+		people, err := a.People(commit)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range people {
+			for j := i + 1; j < len(people); j++ {
+				key := pairKey(people[i], people[j])
+				pairing, ok := byPeople[key]
+				if !ok {
+					pairing = &Pairing{People: key}
+					byPeople[key] = pairing
+				}
+				pairing.Commits++
+				if commit.Time.After(pairing.Last) {
+					pairing.Last = commit.Time
+				}
+			}
+		}
+	}
+
+	for _, pairing := range byPeople {
+		pairings = append(pairings, pairing)
+	}
+	sort.Slice(pairings, func(i, j int) bool {
+		if !pairings[i].Last.Equal(pairings[j].Last) {
+			return pairings[i].Last.Before(pairings[j].Last)
+		}
+		if pairings[i].People[0] != pairings[j].People[0] {
+			return pairings[i].People[0] < pairings[j].People[0]
+		}
+		return pairings[i].People[1] < pairings[j].People[1]
+	})
+
+	return pairings, nil
+}
+
+func pairKey(a, b string) [2]string {
+	if b < a {
+		return [2]string{b, a}
+	}
+	return [2]string{a, b}
+}
@@ -2,11 +2,13 @@ package duet
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
 
@@ -16,8 +18,15 @@ import (
 // Pairs wraps the git authors file with logic for looking up pairs based on initials
 // and building email addresses
 type Pairs struct {
-	file        *pairsFile
-	emailLookup string
+	file   *pairsFile
+	lookup EmailLookup
+	ctx    context.Context
+	runner Runner
+
+	byEmail map[string]*Pair
+	byName  map[string]*Pair
+	// initials missing from the authors file found by the lookup
+	lookedUp map[string]*Pair
 }
 
 // Pair represents a single pair
@@ -26,49 +35,100 @@ type Pair struct {
 	Email    string
 	Initials string
 	Username string
+	// SigningKey is returned by email lookups using the JSON protocol
+	SigningKey string
 }
 
 type pairsFile struct {
-	Pairs          map[string]string `yaml:"authors"`
-	Email          emailConfig       `yaml:"email"`
-	EmailAddresses map[string]string `yaml:"email_addresses"`
-	EmailTemplate  string            `yaml:"email_template"`
+	Pairs          map[string]string   `yaml:"authors"`
+	Email          emailConfig         `yaml:"email,omitempty"`
+	EmailAddresses map[string]string   `yaml:"email_addresses,omitempty"`
+	EmailTemplate  string              `yaml:"email_template,omitempty"`
+	Teams          map[string][]string `yaml:"teams,omitempty"`
+	LDAP           *LDAPConfig         `yaml:"ldap,omitempty"`
 }
 
 type emailConfig struct {
-	Prefix string
-	Domain string
+	Prefix string `yaml:",omitempty"`
+	Domain string `yaml:",omitempty"`
 }
 
 var pairsKey = regexp.MustCompile(`(?m)^pairs:`)
 
 // NewPairsFromFile parses the given yml authors file (see README.md for file structure)
 // Uses emailLookup as external command to determine pair email address if set
+// (see WithEmailLookup for the JSON protocol)
 func NewPairsFromFile(filename string, emailLookup string) (a *Pairs, err error) {
-	af := &pairsFile{}
-
 	file, err := os.Open(filename)
 	if err != nil {
-		return nil, err
+		return nil, withKind(ErrAuthorsFileInvalid, err)
 	}
 	defer file.Close()
 
 	contents, err := ioutil.ReadAll(file)
 	if err != nil {
-		return nil, err
+		return nil, withKind(ErrAuthorsFileInvalid, err)
 	}
 
+	return newPairs(filename, contents, EmailLookup{Command: emailLookup, Protocol: LookupProtocolArgs})
+}
+
+// ReadPairs parses an authors file from r, see NewPairsFromFile
+func ReadPairs(r io.Reader, emailLookup string) (a *Pairs, err error) {
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, withKind(ErrAuthorsFileInvalid, err)
+	}
+
+	return newPairs("authors file", contents, EmailLookup{Command: emailLookup, Protocol: LookupProtocolArgs})
+}
+
+// WithContext returns a copy of the Pairs running the email lookup with the
+// given context
+func (a *Pairs) WithContext(ctx context.Context) *Pairs {
+	pairs := *a
+	pairs.ctx = ctx
+	pairs.byEmail, pairs.byName, pairs.lookedUp = nil, nil, nil
+	return &pairs
+}
+
+// WithRunner returns a copy of the Pairs running the email lookup with the
+// given Runner
+func (a *Pairs) WithRunner(runner Runner) *Pairs {
+	pairs := *a
+	pairs.runner = runner
+	pairs.byEmail, pairs.byName, pairs.lookedUp = nil, nil, nil
+	return &pairs
+}
+
+// WithEmailLookup returns a copy of the Pairs determining emails with the
+// given lookup, e.g. one using the JSON protocol (see EmailLookup)
+func (a *Pairs) WithEmailLookup(lookup EmailLookup) *Pairs {
+	pairs := *a
+	pairs.lookup = lookup
+	pairs.byEmail, pairs.byName, pairs.lookedUp = nil, nil, nil
+	return &pairs
+}
+
+func newPairs(filename string, contents []byte, lookup EmailLookup) (a *Pairs, err error) {
+	af := &pairsFile{}
+
 	// Hack to also support `pairs:` as the key
 	contents = pairsKey.ReplaceAll(contents, []byte("authors:"))
 
 	err = yaml.Unmarshal(contents, &af)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse %s: %+v", filename, err)
+		return nil, withKind(ErrAuthorsFileInvalid, fmt.Errorf("could not parse %s: %+v", filename, err))
+	}
+	if af.LDAP != nil {
+		if err = af.LDAP.validate(); err != nil {
+			return nil, withKind(ErrAuthorsFileInvalid, fmt.Errorf("invalid ldap in %s: %v", filename, err))
+		}
 	}
 
 	return &Pairs{
-		file:        af,
-		emailLookup: emailLookup,
+		file:   af,
+		lookup: lookup,
 	}, nil
 }
 
@@ -80,22 +140,6 @@ var templateFuncs = template.FuncMap{
 }
 
 func (a *Pairs) buildEmail(initials, name, username string) (email string, err error) {
-	if a.emailLookup != "" {
-		var out bytes.Buffer
-
-		cmd := exec.Command(a.emailLookup, initials, name, username)
-		cmd.Stdout = &out
-
-		if err := cmd.Run(); err != nil {
-			return "", err
-		}
-
-		email = strings.TrimSpace(out.String())
-		if email != "" {
-			return email, nil
-		}
-	}
-
 	if e, ok := a.file.EmailAddresses[initials]; ok {
 		email = e
 	} else if a.file.EmailTemplate != "" {
@@ -132,15 +176,22 @@ func (a *Pairs) buildEmail(initials, name, username string) (email string, err e
 // ByInitials returns the pair with the given initials
 // The email is determined from the first non-empty value during the following steps:
 // - Run external lookup if provided during initialization
+// - Search the LDAP directory configured under `ldap` in config
 // - Pull from `email_addresses` map in config
 // - Build using `email_template` if provided
 // - Build using username (if provided) and domain
 // - If two names, build using first initial followed by . followed by last name and domain
 // - If one name, build using name followed by domain
+// With the JSON protocol the lookup (and with a name attribute the LDAP
+// directory) also resolves initials missing from the authors file and may
+// override the name
 func (a *Pairs) ByInitials(initials string) (pair *Pair, err error) {
 	pairString, ok := a.file.Pairs[initials]
 	if !ok {
-		return nil, fmt.Errorf("unknown initials %s", initials)
+		if pair, err = a.lookUpUnknown(initials); pair != nil || err != nil {
+			return pair, err
+		}
+		return nil, &UnknownInitialsError{Initials: initials, Suggestions: a.suggest(initials)}
 	}
 
 	pairParts := strings.SplitN(pairString, ";", 2)
@@ -150,15 +201,169 @@ func (a *Pairs) ByInitials(initials string) (pair *Pair, err error) {
 		username = strings.TrimSpace(pairParts[1])
 	}
 
-	email, err := a.buildEmail(initials, name, username)
-	if err != nil {
-		return nil, err
-	}
-
-	return &Pair{
+	pair = &Pair{
 		Name:     name,
-		Email:    email,
 		Username: username,
 		Initials: initials,
-	}, nil
+	}
+
+	// a failing email lookup leaves the initials as unknown as missing ones
+	if err = a.lookUp(pair, false); err != nil {
+		return nil, withKind(ErrUnknownInitials, err)
+	}
+	if pair.Email == "" {
+		if pair.Email, err = a.buildEmail(initials, name, username); err != nil {
+			return nil, withKind(ErrUnknownInitials, err)
+		}
+	}
+
+	return pair, nil
+}
+
+// lookUp fills in the pair from the email lookup or, if it returns no email,
+// the LDAP directory, leaving it as it is if neither is set or they fail with
+// Fallback set
+// The lookup command is skipped for unknown initials unless it resolves them
+func (a *Pairs) lookUp(pair *Pair, unknown bool) error {
+	ctx := a.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	response := &LookupResponse{}
+	var err error
+	if !unknown || a.lookup.resolvesUnknown() {
+		response, err = a.lookup.Lookup(ctx, a.runner, pair)
+	}
+	if err == nil && response.Email == "" && a.file.LDAP != nil {
+		response, err = a.file.LDAP.Lookup(ctx, a.lookup, pair)
+	}
+	if err != nil {
+		if a.lookup.Fallback {
+			return nil
+		}
+		return err
+	}
+
+	pair.Email = response.Email
+	if response.Name != "" {
+		pair.Name = response.Name
+	}
+	if response.Username != "" {
+		pair.Username = response.Username
+	}
+	pair.SigningKey = response.SigningKey
+	return nil
+}
+
+// lookUpUnknown asks the lookup and the LDAP directory about initials missing
+// from the authors file, returning nil if they don't know them either (or
+// can't tell, see EmailLookup.resolvesUnknown and LDAPConfig.resolvesUnknown)
+func (a *Pairs) lookUpUnknown(initials string) (pair *Pair, err error) {
+	if !a.lookup.resolvesUnknown() && !a.file.LDAP.resolvesUnknown() {
+		return nil, nil
+	}
+	if pair, ok := a.lookedUp[initials]; ok {
+		return pair, nil
+	}
+
+	pair = &Pair{Initials: initials}
+	if err = a.lookUp(pair, true); err != nil {
+		return nil, withKind(ErrUnknownInitials, err)
+	}
+	if pair.Email == "" {
+		pair = nil
+	} else if pair.Name == "" {
+		pair.Name = pair.Email
+	}
+
+	if a.lookedUp == nil {
+		a.lookedUp = map[string]*Pair{}
+	}
+	a.lookedUp[initials] = pair
+	return pair, nil
+}
+
+// All returns every pair in the authors file ordered by initials
+func (a *Pairs) All() (pairs []*Pair, err error) {
+	var initials []string
+	for i := range a.file.Pairs {
+		initials = append(initials, i)
+	}
+	sort.Strings(initials)
+
+	for _, i := range initials {
+		pair, err := a.ByInitials(i)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair)
+	}
+
+	return pairs, nil
+}
+
+// Teams returns the initials of the members of each team in the authors file
+func (a *Pairs) Teams() map[string][]string {
+	return a.file.Teams
+}
+
+// Identify returns the pair matching an identity from git history by email,
+// falling back to the name (nil if nobody matches)
+func (a *Pairs) Identify(identity Identity) (pair *Pair, err error) {
+	if a.byEmail == nil {
+		all, err := a.All()
+		if err != nil {
+			return nil, err
+		}
+
+		a.byEmail = map[string]*Pair{}
+		a.byName = map[string]*Pair{}
+		for _, p := range all {
+			a.byEmail[strings.ToLower(p.Email)] = p
+			a.byName[strings.ToLower(p.Name)] = p
+		}
+	}
+
+	if pair, ok := a.byEmail[strings.ToLower(identity.Email)]; ok {
+		return pair, nil
+	}
+
+	return a.byName[strings.ToLower(strings.TrimSpace(identity.Name))], nil
+}
+
+// Expand replaces team names (from `teams` in the authors file) with the
+// initials of their members, resolves name fragments (see Resolve) and removes
+// duplicates
+// Returns an error for anything that is neither a team nor known initials
+func (a *Pairs) Expand(names ...string) (initials []string, err error) {
+	seen := map[string]bool{}
+	for _, name := range names {
+		members, ok := a.file.Teams[name]
+		if !ok {
+			member, err := a.resolve(name)
+			if err != nil {
+				return nil, err
+			}
+			members = []string{member}
+		}
+
+		for _, member := range members {
+			if _, ok := a.file.Pairs[member]; !ok {
+				pair, err := a.lookUpUnknown(member)
+				if err != nil {
+					return nil, err
+				}
+				if pair == nil {
+					return nil, &UnknownInitialsError{Initials: member}
+				}
+			}
+			if !seen[member] {
+				seen[member] = true
+				initials = append(initials, member)
+			}
+		}
+	}
+
+	return initials, nil
 }
@@ -0,0 +1,277 @@
+package duet
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rotation strategies selectable via Configuration.RotationStrategy
+// RotateRoundRobin moves the first committer to author after every commit
+// RotateEveryNCommits rotates round-robin after every RotationCommits commits
+// RotateInterval rotates round-robin once per elapsed RotationInterval
+// RotateRandomFair makes the least recent author of the pairing the next
+// author (ties broken randomly)
+// RotateAuthorOnly keeps the first committer fixed and rotates the author
+// through everybody else
+const (
+	RotateRoundRobin    = "round-robin"
+	RotateEveryNCommits = "every-n-commits"
+	RotateInterval      = "interval"
+	RotateRandomFair    = "random-fair"
+	RotateAuthorOnly    = "author-only"
+)
+
+// RotationState is the rotation bookkeeping persisted in the git config
+// namespace so that rotation carries over between shells
+// Commits counts the commits since the last rotation
+// Rotated is the time of the last rotation (or when counting started)
+// Authors lists initials from least to most recent author
+type RotationState struct {
+	Commits int
+	Rotated time.Time
+	Authors []string
+}
+
+// RotationStrategy decides who authors the next commit
+// Next is called after every commit and returns the new author and
+// committers, or a nil author to keep the current ones
+type RotationStrategy interface {
+	Next(state *RotationState, now time.Time, author *Pair, committers []*Pair) (*Pair, []*Pair)
+}
+
+// NewRotationStrategy returns the strategy configured in config
+func NewRotationStrategy(config *Configuration) (strategy RotationStrategy, err error) {
+	switch config.RotationStrategy {
+	case RotateRoundRobin:
+		return roundRobin{}, nil
+	case RotateEveryNCommits:
+		if config.RotationCommits < 1 {
+			return nil, fmt.Errorf("rotation commits must be positive, got %d", config.RotationCommits)
+		}
+		return everyNCommits{commits: config.RotationCommits}, nil
+	case RotateInterval:
+		if config.RotationInterval <= 0 {
+			return nil, fmt.Errorf("rotation interval must be positive, got %s", config.RotationInterval)
+		}
+		return interval{interval: config.RotationInterval}, nil
+	case RotateRandomFair:
+		return randomFair{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}, nil
+	case RotateAuthorOnly:
+		return authorOnly{}, nil
+	default:
+		return nil, fmt.Errorf("unknown rotation strategy %s", config.RotationStrategy)
+	}
+}
+
+type roundRobin struct{}
+
+func (roundRobin) Next(state *RotationState, now time.Time, author *Pair, committers []*Pair) (*Pair, []*Pair) {
+	return rotate(author, committers, 1)
+}
+
+// rotate moves the author to the end of the committers and the first
+// committer to author the given number of times
+func rotate(author *Pair, committers []*Pair, times int) (*Pair, []*Pair) {
+	everyone := append([]*Pair{author}, committers...)
+	rotated := make([]*Pair, 0, len(everyone))
+	for i := range everyone {
+		rotated = append(rotated, everyone[(i+times)%len(everyone)])
+	}
+	return rotated[0], rotated[1:]
+}
+
+type everyNCommits struct {
+	commits int
+}
+
+func (s everyNCommits) Next(state *RotationState, now time.Time, author *Pair, committers []*Pair) (*Pair, []*Pair) {
+	if state.Commits < s.commits {
+		return nil, nil
+	}
+	return rotate(author, committers, 1)
+}
+
+type interval struct {
+	interval time.Duration
+}
+
+func (s interval) Next(state *RotationState, now time.Time, author *Pair, committers []*Pair) (*Pair, []*Pair) {
+	if state.Rotated.IsZero() {
+		return nil, nil
+	}
+
+	times := int(now.Sub(state.Rotated) / s.interval)
+	if times == 0 {
+		return nil, nil
+	}
+	// keep the schedule aligned with when the pairing started like a mob timer
+	state.Rotated = state.Rotated.Add(time.Duration(times) * s.interval)
+	return rotate(author, committers, times)
+}
+
+type randomFair struct {
+	rand *rand.Rand
+}
+
+func (s randomFair) Next(state *RotationState, now time.Time, author *Pair, committers []*Pair) (*Pair, []*Pair) {
+	recency := map[string]int{}
+	for i, initials := range state.Authors {
+		recency[initials] = i + 1
+	}
+	// the author just committed, so is the most recent regardless of history
+	recency[author.Initials] = len(state.Authors) + 1
+
+	var candidates []int
+	for i, c := range committers {
+		if len(candidates) == 0 || recency[c.Initials] < recency[committers[candidates[0]].Initials] {
+			candidates = []int{i}
+		} else if recency[c.Initials] == recency[committers[candidates[0]].Initials] {
+			candidates = append(candidates, i)
+		}
+	}
+
+	next := candidates[s.rand.Intn(len(candidates))]
+	rest := append(append([]*Pair{}, committers[:next]...), committers[next+1:]...)
+	return committers[next], append(rest, author)
+}
+
+type authorOnly struct{}
+
+func (authorOnly) Next(state *RotationState, now time.Time, author *Pair, committers []*Pair) (*Pair, []*Pair) {
+	if len(committers) < 2 {
+		return nil, nil
+	}
+
+	next, rest := rotate(author, committers[1:], 1)
+	return next, append([]*Pair{committers[0]}, rest...)
+}
+
+// RotateWith rotates the author and committers as decided by strategy
+// (does nothing if no committer is set)
+// This operates on the config the authorship is drawn from if the scope is
+// Default
+func (gc *GitConfig) RotateWith(strategy RotationStrategy) (err error) {
+	gitConfig := gc
+	if gitConfig.Scope == Default {
+		// find source of configuration
+		if gitConfig, err = gc.AuthorConfig(); err != nil {
+			return err
+		}
+	}
+
+	var author *Pair
+	var committers []*Pair
+
+	if author, err = gc.GetAuthor(); err != nil {
+		return err
+	}
+	if committers, err = gc.GetCommitters(); err != nil {
+		return err
+	}
+
+	if author == nil || len(committers) == 0 {
+		return nil
+	}
+
+	state, err := gitConfig.GetRotationState()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	mtime, err := gitConfig.GetMtime()
+	if err != nil {
+		return err
+	}
+	if mtime.After(state.Rotated) {
+		// the pairing was changed since the last rotation, so start over
+		state.Commits = 0
+		state.Rotated = mtime
+	}
+	state.Commits++
+
+	nextAuthor, nextCommitters := strategy.Next(state, now, author, committers)
+	if nextAuthor != nil {
+		if _, err = gitConfig.setAuthor(nextAuthor); err != nil {
+			return err
+		}
+		if err = gitConfig.setCommitters(nextCommitters); err != nil {
+			return err
+		}
+
+		state.Commits = 0
+		if _, ok := strategy.(interval); !ok {
+			state.Rotated = now
+		}
+	}
+	state.authored(author.Initials)
+
+	return gitConfig.setRotationState(state)
+}
+
+// authored moves initials to the most recent end of Authors
+func (state *RotationState) authored(initials string) {
+	authors := []string{}
+	for _, a := range state.Authors {
+		if a != initials {
+			authors = append(authors, a)
+		}
+	}
+	state.Authors = append(authors, initials)
+}
+
+// GetRotationState returns the persisted rotation bookkeeping
+func (gc *GitConfig) GetRotationState() (state *RotationState, err error) {
+	state = &RotationState{}
+
+	commits, err := gc.getKey("rotation-commits")
+	if err != nil {
+		return nil, err
+	}
+	if commits != "" {
+		if state.Commits, err = strconv.Atoi(commits); err != nil {
+			return nil, err
+		}
+	}
+
+	rotated, err := gc.getKey("rotation-time")
+	if err != nil {
+		return nil, err
+	}
+	if rotated != "" {
+		rotatedUnix, err := strconv.ParseInt(rotated, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		state.Rotated = time.Unix(rotatedUnix, 0)
+	}
+
+	authors, err := gc.getKey("rotation-authors")
+	if err != nil {
+		return nil, err
+	}
+	if authors != "" {
+		state.Authors = strings.Split(authors, delim)
+	}
+
+	return state, nil
+}
+
+func (gc *GitConfig) setRotationState(state *RotationState) (err error) {
+	if err = gc.setKey("rotation-commits", strconv.Itoa(state.Commits)); err != nil {
+		return err
+	}
+
+	if err = gc.setKey("rotation-time", strconv.FormatInt(state.Rotated.Unix(), 10)); err != nil {
+		return err
+	}
+
+	if err = gc.setKey("rotation-authors", strings.Join(state.Authors, delim)); err != nil {
+		return err
+	}
+
+	return nil
+}
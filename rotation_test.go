@@ -0,0 +1,168 @@
+package duet_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	duet "github.com/git-duet/git-duet"
+	"github.com/git-duet/git-duet/duettest"
+)
+
+const mobAuthorsFile = `authors:
+  jd: Jane Doe
+  fb: Frances Bar
+  zs: Zubaz Shirts
+email:
+  domain: hamster.info.local
+`
+
+// pairing sets jd as the author and fb and zs as the committers in the local
+// (or global) config of a fake git
+func pairing(t *testing.T, global bool) (*duettest.Git, *duet.GitConfig) {
+	pairs, err := duet.ReadPairs(strings.NewReader(mobAuthorsFile), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var people []*duet.Pair
+	for _, initials := range []string{"jd", "fb", "zs"} {
+		pair, err := pairs.ByInitials(initials)
+		if err != nil {
+			t.Fatal(err)
+		}
+		people = append(people, pair)
+	}
+
+	git := duettest.NewGit("/src/project")
+	gitConfig := &duet.GitConfig{Namespace: "duet.env", Scope: duet.Local, Runner: git}
+	if global {
+		gitConfig.Scope = duet.Global
+	}
+	if err = gitConfig.SetAuthor(people[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err = gitConfig.SetCommitters(people[1:]...); err != nil {
+		t.Fatal(err)
+	}
+	return git, &duet.GitConfig{Namespace: "duet.env", Runner: git}
+}
+
+// rotationStrategy returns the strategy configured by the git config values
+func rotationStrategy(t *testing.T, values map[string]string) duet.RotationStrategy {
+	config, err := duet.ParseConfiguration(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	strategy, err := duet.NewRotationStrategy(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strategy
+}
+
+// initials returns the author and committers set in the fake git
+func initials(t *testing.T, gitConfig *duet.GitConfig) string {
+	author, err := gitConfig.GetAuthor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	committers, err := gitConfig.GetCommitters()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	everyone := []string{author.Initials}
+	for _, committer := range committers {
+		everyone = append(everyone, committer.Initials)
+	}
+	return strings.Join(everyone, " ")
+}
+
+func TestRotateWith(t *testing.T) {
+	ago := func(d time.Duration) string {
+		return strconv.FormatInt(time.Now().Add(-d).Unix(), 10)
+	}
+
+	for _, test := range []struct {
+		name   string
+		config map[string]string
+		state  map[string]string
+		want   []string
+	}{
+		{
+			name:   "round-robin rotates after every commit",
+			config: map[string]string{"duet.rotation.strategy": duet.RotateRoundRobin},
+			want:   []string{"fb zs jd", "zs jd fb", "jd fb zs"},
+		},
+		{
+			name:   "every-n-commits waits for the commits",
+			config: map[string]string{"duet.rotation.strategy": duet.RotateEveryNCommits, "duet.rotation.commits": "2"},
+			want:   []string{"jd fb zs", "fb zs jd", "fb zs jd", "zs jd fb"},
+		},
+		{
+			name:   "interval rotates once per elapsed interval",
+			config: map[string]string{"duet.rotation.strategy": duet.RotateInterval, "duet.rotation.interval": "10m"},
+			state:  map[string]string{"duet.env.mtime": ago(25 * time.Minute)},
+			want:   []string{"zs jd fb", "zs jd fb"},
+		},
+		{
+			name:   "random-fair picks the least recent author",
+			config: map[string]string{"duet.rotation.strategy": duet.RotateRandomFair},
+			state:  map[string]string{"duet.env.rotation-authors": "zs, +fb"},
+			want:   []string{"zs fb jd", "fb jd zs", "jd zs fb"},
+		},
+		{
+			name:   "author-only keeps the first committer",
+			config: map[string]string{"duet.rotation.strategy": duet.RotateAuthorOnly},
+			want:   []string{"zs fb jd", "jd fb zs"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			strategy := rotationStrategy(t, test.config)
+			git, gitConfig := pairing(t, false)
+			for key, value := range test.state {
+				git.Local[key] = value
+			}
+
+			for i, want := range test.want {
+				if err := gitConfig.RotateWith(strategy); err != nil {
+					t.Fatal(err)
+				}
+				if got := initials(t, gitConfig); got != want {
+					t.Errorf("after commit %d got %q, want %q", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRotateWithRotatesTheScopeOfTheAuthor(t *testing.T) {
+	git, gitConfig := pairing(t, true)
+
+	if err := gitConfig.RotateWith(rotationStrategy(t, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := git.Global["duet.env.git-author-initials"]; got != "fb" {
+		t.Errorf("got global author %q, want fb", got)
+	}
+	if _, ok := git.Local["duet.env.git-author-initials"]; ok {
+		t.Errorf("rotation set a local author")
+	}
+}
+
+func TestRotateWithoutCommitters(t *testing.T) {
+	git := duettest.NewGit("/src/project")
+	gitConfig := &duet.GitConfig{Namespace: "duet.env", Scope: duet.Local, Runner: git}
+	if err := gitConfig.SetAuthor(&duet.Pair{Initials: "jd", Name: "Jane Doe", Email: "jane@hamster.info.local"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gitConfig.RotateWith(rotationStrategy(t, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := git.Local["duet.env.rotation-commits"]; ok {
+		t.Errorf("rotation state written without committers")
+	}
+}
@@ -0,0 +1,228 @@
+package duet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Runner runs the commands git-duet depends on: git and the email lookup
+// command
+// ExecRunner runs them for real, the duettest package has an in-memory fake
+type Runner interface {
+	Run(ctx context.Context, cmd *Command) error
+}
+
+// Command is a command to run: Env is added to the environment of the
+// current process, nil Stdin, Stdout and Stderr are the null device (like
+// with os/exec)
+// A Runner returns an error with an ExitCode method (like *exec.ExitError or
+// *ExitError) if the command exits with a non zero status
+type Command struct {
+	Name   string
+	Args   []string
+	Dir    string
+	Env    []string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// ExitError is returned by Runners other than ExecRunner for a command
+// exiting with a non zero status
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the exit status of the command
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// ExecRunner runs commands with os/exec
+type ExecRunner struct{}
+
+// Run runs the command, killing it once ctx is done
+// A command that can be cancelled runs in a process group of its own, so that
+// the processes it started are killed with it instead of holding on to its
+// output (it isn't attached to the terminal then, which only commands run
+// without a deadline, like git commit starting an editor, need)
+func (ExecRunner) Run(ctx context.Context, c *Command) error {
+	cmd := exec.Command(c.Name, c.Args...)
+	cmd.Dir = c.Dir
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	if c.Stdin != nil {
+		cmd.Stdin = c.Stdin
+	}
+	if c.Stdout != nil {
+		cmd.Stdout = c.Stdout
+	}
+	if c.Stderr != nil {
+		cmd.Stderr = c.Stderr
+	}
+
+	if ctx.Done() == nil {
+		return cmd.Run()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	startProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd)
+		case <-exited:
+		}
+	}()
+	err := cmd.Wait()
+	close(exited)
+	return err
+}
+
+func runnerOrDefault(runner Runner) Runner {
+	if runner == nil {
+		return ExecRunner{}
+	}
+	return runner
+}
+
+// run runs the command, exiting with one of validFailureCodes is not an error
+func run(ctx context.Context, runner Runner, cmd *Command, validFailureCodes ...int) error {
+	err := runnerOrDefault(runner).Run(ctx, cmd)
+	if code, ok := exitStatus(err); ok {
+		for _, validFailureCode := range validFailureCodes {
+			if code == validFailureCode {
+				return nil
+			}
+		}
+	}
+	return err
+}
+
+// exitStatus returns the exit status of a command that failed with err
+func exitStatus(err error) (code int, ok bool) {
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), true
+	}
+	return 0, false
+}
+
+// TopLevel returns the root of the working tree containing dir (the current
+// directory if empty)
+func TopLevel(ctx context.Context, runner Runner, dir string) (string, error) {
+	output := new(bytes.Buffer)
+	err := run(ctx, runner, &Command{
+		Name:   "git",
+		Args:   []string{"rev-parse", "--show-toplevel"},
+		Dir:    dir,
+		Stdout: output,
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output.String()), nil
+}
+
+// HooksDir returns the directory git runs the hooks of the repository
+// containing dir (the current directory if empty) from, taking GIT_DIR and
+// core.hooksPath into account
+func HooksDir(ctx context.Context, runner Runner, dir string) (string, error) {
+	output := new(bytes.Buffer)
+	err := run(ctx, runner, &Command{
+		Name:   "git",
+		Args:   []string{"rev-parse", "--git-path", "hooks"},
+		Dir:    dir,
+		Stdout: output,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	hooks := strings.TrimSpace(output.String())
+	if !filepath.IsAbs(hooks) {
+		if dir == "" {
+			dir = "."
+		}
+		hooks = filepath.Join(dir, hooks)
+	}
+	return filepath.Abs(hooks)
+}
+
+// Submodule is an initialized submodule: Path is how git displays it
+// (relative to the directory it was listed from), Dir its absolute path
+type Submodule struct {
+	Path string
+	Dir  string
+}
+
+// Submodules returns the initialized submodules of the repository containing
+// dir (the current directory if empty), nested ones following their parent
+func Submodules(ctx context.Context, runner Runner, dir string) (submodules []Submodule, err error) {
+	output := new(bytes.Buffer)
+	err = run(ctx, runner, &Command{
+		Name:   "git",
+		Args:   []string{"submodule", "--quiet", "foreach", "--recursive", `printf '%s\0%s/%s\0' "$displaypath" "$toplevel" "$sm_path"`},
+		Dir:    dir,
+		Stdout: output,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Split(output.String(), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		submodules = append(submodules, Submodule{Path: fields[i], Dir: filepath.Clean(fields[i+1])})
+	}
+	return submodules, nil
+}
+
+// Head returns the commit checked out in the repository containing dir (the
+// current directory if empty), empty if there is none yet
+func Head(ctx context.Context, runner Runner, dir string) (string, error) {
+	output := new(bytes.Buffer)
+	err := run(ctx, runner, &Command{
+		Name:   "git",
+		Args:   []string{"rev-parse", "--quiet", "--verify", "HEAD"},
+		Dir:    dir,
+		Stdout: output,
+	}, 1)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output.String()), nil
+}
+
+// CurrentBranch returns the name of the branch checked out in the repository
+// containing dir (the current directory if empty), empty if HEAD is detached
+func CurrentBranch(ctx context.Context, runner Runner, dir string) (string, error) {
+	output := new(bytes.Buffer)
+	err := run(ctx, runner, &Command{
+		Name:   "git",
+		Args:   []string{"symbolic-ref", "--quiet", "--short", "HEAD"},
+		Dir:    dir,
+		Stdout: output,
+	}, 1)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output.String()), nil
+}
@@ -0,0 +1,34 @@
+package duet_test
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	duet "github.com/git-duet/git-duet"
+)
+
+func TestExecRunnerKillsTheChildrenOfCommands(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("needs sh")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// sleep holds on to the output after sh is killed, unless it's killed too
+	output := new(bytes.Buffer)
+	start := time.Now()
+	err := duet.ExecRunner{}.Run(ctx, &duet.Command{Name: "sh", Args: []string{"-c", "sleep 3; echo done"}, Stdout: output})
+	if err == nil {
+		t.Errorf("the command wasn't killed")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("waited %s for the children of the command", elapsed)
+	}
+	if output.Len() != 0 {
+		t.Errorf("got output %q", output)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package duet
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func startProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,13 @@
+package duet
+
+import (
+	"os/exec"
+)
+
+// Windows has no process groups to start the command in, only the command
+// itself is killed
+func startProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,97 @@
+package duet
+
+import (
+	"fmt"
+	"time"
+)
+
+// Points of reference for the staleness cutoff
+// StaleSinceSet measures from when the pairing was last set
+// StaleSinceCommit measures from the last commit made by the pairing (or when
+// it was set if nothing has been committed since)
+const (
+	StaleSinceSet    = "set"
+	StaleSinceCommit = "commit"
+)
+
+// parseClock parses a local time of day such as 18:00 into the offset from
+// midnight
+func parseClock(clock string) (offset time.Duration, err error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %s (expected HH:MM)", clock)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// LastActive returns the time staleness is measured from according to
+// StaleSince
+func (c *Configuration) LastActive(gc *GitConfig) (last time.Time, err error) {
+	if last, err = gc.GetMtime(); err != nil {
+		return time.Time{}, err
+	}
+
+	if c.StaleSince == StaleSinceCommit {
+		activity, err := gc.GetActivity()
+		if err != nil {
+			return time.Time{}, err
+		}
+		// activity from a previous pairing predates the mtime of the current one
+		if activity.After(last) {
+			last = activity
+		}
+	}
+
+	return last, nil
+}
+
+// Expiry returns when the pairing in gc expires because of ExpireAt (zero
+// Time if no expiry is configured or the pairing was never set)
+func (c *Configuration) Expiry(gc *GitConfig) (expiry time.Time, err error) {
+	if c.ExpireAt == "" {
+		return time.Time{}, nil
+	}
+
+	offset, err := parseClock(c.ExpireAt)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	mtime, err := gc.GetMtime()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if mtime.IsZero() {
+		return time.Time{}, nil
+	}
+
+	mtime = mtime.Local()
+	expiry = time.Date(mtime.Year(), mtime.Month(), mtime.Day(), 0, 0, 0, 0, time.Local).Add(offset)
+	if expiry.Before(mtime) {
+		expiry = expiry.AddDate(0, 0, 1)
+	}
+
+	return expiry, nil
+}
+
+// IsStale reports whether the pairing in gc is stale at the given time
+// A pairing is stale once it has been inactive for longer than StaleCutoff or
+// it is past its expiry
+func (c *Configuration) IsStale(gc *GitConfig, now time.Time) (stale bool, err error) {
+	last, err := c.LastActive(gc)
+	if err != nil {
+		return false, err
+	}
+
+	if last.Add(c.StaleCutoff).Before(now) {
+		return true, nil
+	}
+
+	expiry, err := c.Expiry(gc)
+	if err != nil {
+		return false, err
+	}
+
+	return !expiry.IsZero() && !now.Before(expiry), nil
+}
@@ -683,3 +683,12 @@ load test_helper
   assert_line "GIT_COMMITTER_NAME='Frances Bar'"
   assert_line "GIT_COMMITTER_EMAIL='f.bar@hamster.info.local'"
 }
+
+@test "as duet: --recurse-submodules sets the author and committers in the submodules" {
+  add_submodule lib
+  git as -q --recurse-submodules jd fb
+  run git -C lib config --local "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'jd'
+  run git -C lib config --local "$GIT_DUET_CONFIG_NAMESPACE.git-committer-initials"
+  assert_success 'fb'
+}
@@ -0,0 +1,271 @@
+#!/usr/bin/env bats
+
+load test_helper
+
+@test "authors import: proposes authors from history" {
+  git commit -q --allow-empty -m 'first' --author 'Jane Doe <jane@old.example.local>'
+  git commit -q --allow-empty -m 'second' --author 'Jane Doe <jane@example.local>'
+  git commit -q --allow-empty -m 'third
+
+Co-authored-by: Frances Bar <frances@example.local>'
+
+  run git duet authors import --yes -o -
+  assert_success
+  assert_equal 'authors:
+  fb: Frances Bar
+  jd: Jane Doe
+  jdo: Jane Doe
+  tu: Test User
+email:
+  domain: example.local
+email_addresses:
+  fb: frances@example.local
+  jd: jane@example.local
+  jdo: jane@old.example.local
+  tu: test@example.com' "$(echo "$output" | sed '1,/^---$/d')"
+}
+
+@test "authors import: limits the import to a revision range" {
+  git commit -q --allow-empty -m 'first' --author 'Jane Doe <jane@example.local>'
+  git tag v1
+  git commit -q --allow-empty -m 'second' --author 'Frances Bar <frances@example.local>'
+  git checkout -q -b other v1
+  git commit -q --allow-empty -m 'elsewhere' --author 'Zubaz Shirts <zubaz@example.local>'
+  git checkout -q -
+
+  run git duet authors import v1.. --yes -o -
+  assert_success
+  assert_line '  fb: Frances Bar'
+  refute_line '  jd: Jane Doe'
+  refute_line '  zs: Zubaz Shirts'
+}
+
+@test "authors import: groups emails and names of the same person" {
+  git commit -q --allow-empty -m 'first' --author 'Jane Doe <jane@example.local>'
+  git commit -q --allow-empty -m 'second' --author 'jane <jane@example.local>'
+
+  run git duet authors import --yes -o -
+  assert_success
+  assert_line '  jd: Jane Doe'
+  refute_line '  ja: jane'
+}
+
+@test "authors import: reports people sharing a name as possible duplicates" {
+  git commit -q --allow-empty -m 'first' --author 'root <root@build.example.local>'
+  git commit -q --allow-empty -m 'second' --author 'root <root@laptop.example.local>'
+
+  run git duet authors import --yes -o -
+  assert_success
+  assert_line 'possible duplicates, answer the initials of the first to merge them: ro <root@laptop.example.local>, roo <root@build.example.local>'
+  assert_line '  ro: root@laptop.example.local'
+  assert_line '  roo: root@build.example.local'
+}
+
+@test "authors import: merges possible duplicates in review" {
+  git commit -q --allow-empty -m 'first' --author 'Jane Doe <jane@old.example.local>'
+  git commit -q --allow-empty -m 'second' --author 'Jane Doe <jane@example.local>'
+
+  run bash -c "printf '\njd\n\n' | git duet authors import -o - 2>/dev/null"
+  assert_success
+  assert_line '  jd: jane@example.local # also jane@old.example.local'
+  refute_line '  jdo: Jane Doe'
+}
+
+@test "authors import: annotates initials that YAML quotes" {
+  git commit -q --allow-empty -m 'first' --author 'Oscar Nash <oscar@old.example.local>'
+  git commit -q --allow-empty -m 'second' --author 'Oscar Nash <oscar@example.local>'
+
+  run bash -c "printf '\non\n\n' | git duet authors import -o - 2>/dev/null"
+  assert_success
+  assert_line '  "on": oscar@example.local # also oscar@old.example.local'
+}
+
+@test "authors import: resolves colliding initials" {
+  git commit -q --allow-empty -m 'first' --author 'Jane Doe <jane@example.local>'
+  git commit -q --allow-empty -m 'second' --author 'Jack Dawson <jack@example.local>'
+
+  run git duet authors import --yes -o -
+  assert_success
+  assert_line '  jd: Jack Dawson'
+  assert_line '  jdo: Jane Doe'
+}
+
+@test "authors import: lets initials be changed or people be skipped in review" {
+  git commit -q --allow-empty -m 'first' --author 'Jane Doe <jane@example.local>'
+  git commit -q --allow-empty -m 'second' --author 'Jack Dawson <jack@example.local>'
+
+  run bash -c "printf 'jack\n\n-\n' | git duet authors import -o - 2>/dev/null"
+  assert_success
+  assert_line '  jack: Jack Dawson'
+  assert_line '  jdo: Jane Doe'
+  refute_line '  tu: Test User'
+}
+
+@test "authors import: refuses taken initials in review" {
+  git commit -q --allow-empty -m 'first' --author 'Jane Doe <jane@example.local>'
+
+  run bash -c "printf 'tu\njane\n\n' | git duet authors import -o - 2>&1"
+  assert_success
+  [[ "$output" == *"initials tu are already taken"* ]]
+  assert_line '  jane: Jane Doe'
+}
+
+@test "authors import: writes a file git duet can use" {
+  git commit -q --allow-empty -m 'first' --author 'Jane Doe <jane@example.local>'
+  unset GIT_DUET_AUTHORS_FILE
+
+  run git duet authors import --yes
+  assert_success
+  assert_output "git-duet-authors: wrote 2 authors to $GIT_DUET_TEST_REPO/.git-authors"
+
+  run git duet jd tu
+  assert_success
+  assert_line "GIT_AUTHOR_EMAIL='jane@example.local'"
+}
+
+@test "authors add: adds an author keeping comments and order" {
+  cat > "$GIT_DUET_AUTHORS_FILE" <<EOF
+---
+# our team
+pairs:
+  jd: Jane Doe # lead
+  fb: Frances Bar
+
+email:
+  domain: hamster.info.local
+
+email_addresses:
+  jd: jane@hamsters.biz.local
+EOF
+
+  run git duet authors add zp Zubaz Pants --email zubaz@pants.local --username zubaz
+  assert_success
+  run cat "$GIT_DUET_AUTHORS_FILE"
+  assert_output '---
+# our team
+pairs:
+  jd: Jane Doe # lead
+  fb: Frances Bar
+  zp: Zubaz Pants; zubaz
+
+email:
+  domain: hamster.info.local
+
+email_addresses:
+  jd: jane@hamsters.biz.local
+  zp: zubaz@pants.local'
+
+  run git duet jd zp
+  assert_success
+  assert_line "GIT_COMMITTER_EMAIL='zubaz@pants.local'"
+}
+
+@test "authors add: creates email_addresses if needed" {
+  clear_custom_email_template
+  grep -v "^email_addresses:\|^  [a-z][a-z]: .*@" "$GIT_DUET_AUTHORS_FILE" > "$GIT_DUET_AUTHORS_FILE.new"
+  mv "$GIT_DUET_AUTHORS_FILE.new" "$GIT_DUET_AUTHORS_FILE"
+
+  run git duet authors add --email new@hamster.info.local nh New Hire
+  assert_success
+  run tail -2 "$GIT_DUET_AUTHORS_FILE"
+  assert_output 'email_addresses:
+  nh: new@hamster.info.local'
+}
+
+@test "authors add: refuses duplicate initials" {
+  run git duet authors add jd Jack Dawson
+  assert_failure
+  assert_output 'initials jd are already taken by Jane Doe'
+}
+
+@test "authors add: refuses invalid email addresses" {
+  cp "$GIT_DUET_AUTHORS_FILE" "$GIT_DUET_TEST_DIR/before"
+  run git duet authors add nh New Hire --email 'not an email'
+  assert_failure
+  assert_output 'invalid email address "not an email" for nh'
+  run diff "$GIT_DUET_TEST_DIR/before" "$GIT_DUET_AUTHORS_FILE"
+  assert_success
+}
+
+@test "authors add: quotes names that are not plain YAML" {
+  run git duet authors add nh 'Hire: New'
+  assert_success
+  run git solo nh
+  assert_line "GIT_AUTHOR_NAME='Hire: New'"
+}
+
+@test "authors add: takes a lone - as part of the name" {
+  run timeout 5 git duet authors add zz Zed -
+  assert_success
+  run git solo zz
+  assert_line "GIT_AUTHOR_NAME='Zed -'"
+}
+
+@test "authors add: takes everything after -- as the name" {
+  run git duet authors add --email zed@hamster.info.local zz -- Zed --email
+  assert_success
+  run git solo zz
+  assert_line "GIT_AUTHOR_NAME='Zed --email'"
+  assert_line "GIT_AUTHOR_EMAIL='zed@hamster.info.local'"
+}
+
+@test "authors remove: removes the author and their email address" {
+  run git duet authors remove fb
+  assert_success
+  refute_line '  fb: Frances Bar'
+  run grep -c "fb:" "$GIT_DUET_AUTHORS_FILE"
+  assert_output '0'
+}
+
+@test "authors remove: removes the author from teams" {
+  cat >> "$GIT_DUET_AUTHORS_FILE" <<EOF
+teams:
+  core: [jd, fb, al]
+  ops:
+    - fb
+    - on
+EOF
+
+  run git duet authors remove fb
+  assert_success
+  run tail -4 "$GIT_DUET_AUTHORS_FILE"
+  assert_output 'teams:
+  core: [jd, al]
+  ops:
+    - on'
+}
+
+@test "authors remove: fails on unknown initials" {
+  run git duet authors remove xx
+  assert_failure
+  assert_output 'unknown initials xx'
+}
+
+@test "authors rename: renames the author, email address and team memberships" {
+  cat >> "$GIT_DUET_AUTHORS_FILE" <<EOF
+teams:
+  core: [jd, fb]
+EOF
+
+  run git duet authors rename fb fra
+  assert_success
+  run grep "fra" "$GIT_DUET_AUTHORS_FILE"
+  assert_output '  fra: Frances Bar
+  fra: f.bar@hamster.info.local
+  core: [jd, fra]'
+}
+
+@test "authors rename: refuses taken initials" {
+  run git duet authors rename fb jd
+  assert_failure
+  assert_output 'initials jd are already taken'
+}
+
+@test "authors: -C edits the authors file of another repository" {
+  unset GIT_DUET_AUTHORS_FILE
+  printf 'authors:\n  jd: Jane Doe\nemail:\n  domain: example.local\n' > .git-authors
+  cd "$GIT_DUET_TEST_DIR"
+  run git duet authors -C repo add ab Alice Bobbins
+  assert_success
+  grep 'ab: Alice Bobbins' repo/.git-authors
+}
@@ -0,0 +1,99 @@
+#!/usr/bin/env bats
+
+load test_helper
+
+@test "board set: puts the pairing of a branch on the board" {
+  run git duet board set --branch feature jd fb
+  assert_success
+  run cat .git-duet/board.yaml
+  assert_output '---
+branches:
+  feature: jd fb'
+}
+
+@test "board set: puts the pairing of this machine on the board by default" {
+  git duet board set al on
+  run cat .git-duet/board.yaml
+  assert_line "  $(uname -n | cut -d. -f1): al on"
+}
+
+@test "board set: keeps comments and replaces entries" {
+  mkdir .git-duet
+  cat > .git-duet/board.yaml <<EOF
+# monday
+hosts:
+  station-1: jd fb # by the window
+  station-2: al on
+EOF
+  git duet board set --host station-2 zp zs
+  run cat .git-duet/board.yaml
+  assert_output '# monday
+hosts:
+  station-1: jd fb # by the window
+  station-2: zp zs'
+}
+
+@test "board set: removes the entry without initials" {
+  git duet board set --branch feature jd fb
+  git duet board set --branch main al on
+  git duet board set --branch feature
+  run cat .git-duet/board.yaml
+  assert_output '---
+branches:
+  main: al on'
+}
+
+@test "board set: fails on unknown initials" {
+  run git duet board set --branch feature jd xx
+  assert_failure
+  assert_line 'unknown initials xx'
+  [ ! -f .git-duet/board.yaml ]
+}
+
+@test "board apply: sets the pairing of the current branch" {
+  git duet board set --branch master jd fb
+  run git duet board apply
+  assert_success
+  assert_line 0 'branch master: jd fb'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'jd'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-initials"
+  assert_success 'fb'
+}
+
+@test "board apply: sets the pairing of this machine" {
+  git duet board set --host "$(uname -n)" al
+  git duet board set --branch other jd fb
+  run git duet board apply -q
+  assert_success ''
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'al'
+}
+
+@test "board apply: expands teams" {
+  cat >> "$GIT_DUET_AUTHORS_FILE" <<EOF
+teams:
+  core: [jd, fb]
+EOF
+  git duet board set --branch master core
+  git duet board apply -q
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-initials"
+  assert_success 'fb'
+}
+
+@test "board apply: fails if nobody is on the board for the branch or machine" {
+  git duet board set --branch other jd fb
+  run git duet board apply
+  assert_failure
+  [[ "$output" == "nobody on the board for branch master or host "* ]]
+}
+
+@test "board: -C applies the board of another repository" {
+  git duet board set --branch master jd fb
+  cd "$GIT_DUET_TEST_DIR"
+  run git duet -C repo board apply
+  assert_success
+  assert_line 0 'branch master: jd fb'
+  run git -C repo config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'jd'
+}
@@ -238,6 +238,18 @@ load test_helper
   assert_line "git-author not set"
 }
 
+@test "exits with 89 if no duet pair set" {
+  add_file
+  run git duet-commit -q -m 'Testing set of alpha as author'
+  assert_equal 89 "$status"
+}
+
+@test "exits with the exit code of git if it fails" {
+  git duet -q jd fb
+  run git duet-commit --no-such-option
+  assert_equal 129 "$status"
+}
+
 @test "rejects commits with stale soloists with hook" {
   # if in CI, git-duet-pre-commit will not be in the PATH
   # exposed to git hooks
@@ -273,3 +285,186 @@ load test_helper
   assert_failure
   assert_line "your git duet settings are stale"
 }
+
+@test "writes activity to config" {
+  git duet -q jd fb
+  add_file
+  git duet-commit -q -m 'Testing activity'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.activity"
+  assert_success
+}
+
+@test "rotates every n commits with the every-n-commits strategy" {
+  git duet -q jd fb
+  export GIT_DUET_ROTATE_AUTHOR=1
+  git config duet.rotation.strategy every-n-commits
+  git config duet.rotation.commits 2
+
+  add_file first.txt
+  git duet-commit -q -m 'Testing jd as author'
+  add_file second.txt
+  git duet-commit -q -m 'Testing jd as author again'
+  run git log -1 --format='%an'
+  assert_success 'Jane Doe'
+
+  add_file third.txt
+  git duet-commit -q -m 'Testing fb as author'
+  run git log -1 --format='%an'
+  assert_success 'Frances Bar'
+}
+
+@test "rotates once per elapsed interval with the interval strategy" {
+  git duet -q jd fb
+  git config "$GIT_DUET_CONFIG_NAMESPACE.mtime" "$(( $(date +%s) - 65))"
+  export GIT_DUET_ROTATE_AUTHOR=1
+  export GIT_DUET_ROTATION_STRATEGY=interval
+  export GIT_DUET_ROTATION_INTERVAL=1m
+
+  add_file first.txt
+  git duet-commit -q -m 'Testing jd as author'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'fb'
+
+  add_file second.txt
+  git duet-commit -q -m 'Testing fb as author'
+  run git log -1 --format='%an'
+  assert_success 'Frances Bar'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'fb'
+}
+
+@test "lets everybody author once with the random-fair strategy" {
+  git duet -q jd fb zs
+  export GIT_DUET_ROTATE_AUTHOR=1
+  export GIT_DUET_ROTATION_STRATEGY=random-fair
+
+  add_file first.txt
+  git duet-commit -q -m 'first'
+  add_file second.txt
+  git duet-commit -q -m 'second'
+  add_file third.txt
+  git duet-commit -q -m 'third'
+  run bash -c "git log -3 --format='%an' | sort -u | wc -l | xargs"
+  assert_success '3'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'jd'
+}
+
+@test "keeps the committer fixed with the author-only strategy" {
+  git duet -q jd fb zs
+  export GIT_DUET_ROTATE_AUTHOR=1
+  export GIT_DUET_ROTATION_STRATEGY=author-only
+
+  add_file first.txt
+  git duet-commit -q -m 'Testing jd as author'
+  add_file second.txt
+  git duet-commit -q -m 'Testing zs as author'
+  run git log -1 --format='%an / %cn'
+  assert_success 'Zubaz Shirts / Frances Bar'
+
+  add_file third.txt
+  git duet-commit -q -m 'Testing jd as author'
+  run git log -1 --format='%an / %cn'
+  assert_success 'Jane Doe / Frances Bar'
+}
+
+@test "rejects unknown rotation strategies" {
+  git duet -q jd fb
+  add_file
+  GIT_DUET_ROTATE_AUTHOR=1 GIT_DUET_ROTATION_STRATEGY=sometimes run git duet-commit -q -m 'Testing strategy'
+  assert_failure
+  assert_output 'unknown rotation strategy sometimes'
+}
+
+@test "uses the driver reported by a mob timer as author" {
+  git duet -q jd fb zs
+  echo '{"driver": "zs"}' > "$GIT_DUET_TEST_DIR/timer.json"
+  export GIT_DUET_DRIVER_SOURCE="$GIT_DUET_TEST_DIR/timer.json"
+
+  add_file
+  git duet-commit -q -m 'Testing zs as driver'
+  run git log -1 --format='%an / %cn'
+  assert_success 'Zubaz Shirts / Jane Doe'
+}
+
+@test "matches the mob timer driver by name" {
+  git duet -q jd fb
+  echo '{"driver": "frances bar"}' > "$GIT_DUET_TEST_DIR/timer.json"
+  export GIT_DUET_DRIVER_SOURCE="$GIT_DUET_TEST_DIR/timer.json"
+
+  add_file
+  git duet-commit -q -m 'Testing fb as driver'
+  run git log -1 --format='%an / %cn'
+  assert_success 'Frances Bar / Jane Doe'
+}
+
+@test "does not rotate when following a mob timer" {
+  git duet -q jd fb
+  echo '{"driver": "jd"}' > "$GIT_DUET_TEST_DIR/timer.json"
+  export GIT_DUET_DRIVER_SOURCE="$GIT_DUET_TEST_DIR/timer.json"
+
+  add_file
+  GIT_DUET_ROTATE_AUTHOR=1 git duet-commit -q -m 'Testing jd as driver'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'jd'
+}
+
+@test "ignores a missing mob timer file" {
+  git duet -q jd fb
+  export GIT_DUET_DRIVER_SOURCE="$GIT_DUET_TEST_DIR/missing.json"
+
+  add_file
+  git duet-commit -q -m 'Testing jd as author'
+  run git log -1 --format='%an'
+  assert_success 'Jane Doe'
+}
+
+@test "rejects a mob timer driver outside of the pairing" {
+  git duet -q jd fb
+  echo '{"driver": "al"}' > "$GIT_DUET_TEST_DIR/timer.json"
+  export GIT_DUET_DRIVER_SOURCE="$GIT_DUET_TEST_DIR/timer.json"
+
+  add_file
+  run git duet-commit -q -m 'Testing al as driver'
+  assert_failure
+  assert_output 'driver al is not part of the current pairing'
+}
+
+@test "writes a note listing every participant if GIT_DUET_NOTES" {
+  export GIT_DUET_NOTES=1
+  git duet -q jd fb zs
+  add_file
+  git duet-commit -q -m 'Testing the note'
+
+  run git notes --ref duet show HEAD
+  assert_success
+  assert_line 0 'participants:'
+  assert_line 1 '- initials: jd'
+  assert_line 2 '  name: Jane Doe'
+  assert_line 3 '  email: jane@hamsters.biz.local'
+  assert_line 4 '  position: 0'
+  assert_line '- initials: zs'
+  assert_line '  position: 2'
+}
+
+@test "writes the note for the pairing before rotating" {
+  export GIT_DUET_NOTES=1
+  export GIT_DUET_ROTATE_AUTHOR=1
+  git duet -q jd fb
+  add_file
+  git duet-commit -q -m 'Testing the note'
+
+  run git notes --ref duet show HEAD
+  assert_line 1 '- initials: jd'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'fb'
+}
+
+@test "does not write notes by default" {
+  git duet -q jd fb
+  add_file
+  git duet-commit -q -m 'Testing without a note'
+
+  run git notes --ref duet show HEAD
+  assert_failure
+}
@@ -0,0 +1,119 @@
+#!/usr/bin/env bats
+
+load test_helper
+
+# complete prints the bash completions for the given words, the last one being
+# completed
+complete() {
+  bash -c 'source <(git duet completion bash)
+COMP_WORDS=("$@")
+COMP_CWORD=$((${#COMP_WORDS[@]} - 1))
+__git_duet
+echo "${COMPREPLY[*]}"' complete "$@"
+}
+
+@test "authors --complete: prints initials and names" {
+  run git duet authors --complete
+  assert_success
+  assert_line 0 "al	Abraham Lincoln"
+  assert_line 1 "fb	Frances Bar"
+  assert_line 5 "zs	Zubaz Shirts"
+}
+
+@test "authors --complete: prints teams" {
+  cat >> "$GIT_DUET_AUTHORS_FILE" <<EOF
+teams:
+  core: [jd, fb]
+EOF
+
+  run git duet authors --complete
+  assert_success
+  assert_line 6 "core	team: jd fb"
+}
+
+@test "completion: fails on unknown shells" {
+  run git duet completion tcsh
+  assert_failure
+  assert_output 'unknown shell tcsh (expected bash, zsh, fish)'
+}
+
+@test "completion bash: is valid" {
+  git duet completion bash > "$GIT_DUET_TEST_DIR/completion.bash"
+  run bash -n "$GIT_DUET_TEST_DIR/completion.bash"
+  assert_success
+}
+
+@test "completion bash: completes subcommands and initials" {
+  run complete git-duet ''
+  assert_success 'authors blame board cache completion config mailmap shortlog stats status suggest al fb jd on zp zs'
+}
+
+@test "completion bash: completes initials after initials" {
+  run complete git-duet jd z
+  assert_success 'zp zs'
+}
+
+@test "completion bash: completes teams" {
+  cat >> "$GIT_DUET_AUTHORS_FILE" <<EOF
+teams:
+  core: [jd, fb]
+EOF
+
+  run complete git duet suggest c
+  assert_success 'core'
+}
+
+@test "completion bash: completes flags" {
+  run complete git-solo --
+  assert_success '--global --help --quiet --recurse-submodules --refresh --show --version'
+}
+
+@test "completion bash: completes subcommand flags" {
+  run complete git duet stats --f
+  assert_success '--format'
+}
+
+@test "completion bash: completes nested subcommands" {
+  run complete git duet authors r
+  assert_success 'remove rename'
+}
+
+@test "completion bash: completes hook types" {
+  run complete git-duet-install-hook pre
+  assert_success 'pre-commit prepare-commit-msg'
+}
+
+@test "completion zsh: is valid" {
+  if ! command -v zsh >/dev/null; then
+    skip "zsh is not installed"
+  fi
+  git duet completion zsh > "$GIT_DUET_TEST_DIR/completion.zsh"
+  run zsh -n "$GIT_DUET_TEST_DIR/completion.zsh"
+  assert_success
+}
+
+@test "completion fish: is valid" {
+  if ! command -v fish >/dev/null; then
+    skip "fish is not installed"
+  fi
+  git duet completion fish > "$GIT_DUET_TEST_DIR/completion.fish"
+  run fish -n "$GIT_DUET_TEST_DIR/completion.fish"
+  assert_success
+}
+
+@test "completion fish: completes initials with names" {
+  run git duet completion fish
+  assert_success
+  assert_line "complete -c git-solo -f -n '__git_duet_at solo' -a '(git duet authors --complete 2>/dev/null)'"
+}
+
+@test "completion bash: completes directories after -C" {
+  mkdir -p "$GIT_DUET_TEST_DIR/repo/subdir"
+  run complete git-duet -C sub
+  assert_success 'subdir'
+}
+
+@test "completion bash: completes subcommands after -C <path>" {
+  run complete git-duet -C . a
+  assert_success 'authors al'
+}
@@ -0,0 +1,68 @@
+#!/usr/bin/env bats
+
+load test_helper
+
+@test "config: lists defaults" {
+  run git duet config --list
+  assert_success
+  assert_line "default	duet.rotateAuthor=0"
+  assert_line "default	duet.stalePolicy=block"
+  assert_line "default	duet.setGitUserConfig=0"
+}
+
+@test "config: lists environment variables" {
+  GIT_DUET_CO_AUTHORED_BY=1 run git duet config --list
+  assert_success
+  assert_line "env	duet.coAuthoredBy=1"
+  assert_line "env	duet.configNamespace=foo.bar"
+  assert_line "default	duet.setGitUserConfig=1"
+}
+
+@test "config: requires --list" {
+  run git duet config
+  assert_failure
+}
+
+@test "config: reads options from the repository git config" {
+  git config duet.coAuthoredBy true
+  run git duet config --list
+  assert_line "local	duet.coAuthoredBy=true"
+
+  git duet -q jd fb
+  [ -f .git/hooks/prepare-commit-msg ]
+}
+
+@test "config: reads options from the global git config" {
+  export GIT_CONFIG_GLOBAL="$GIT_DUET_TEST_DIR/gitconfig"
+  git config --global duet.rotateAuthor yes
+  run git duet config --list
+  assert_line "global	duet.rotateAuthor=yes"
+}
+
+@test "config: repository git config wins over global" {
+  export GIT_CONFIG_GLOBAL="$GIT_DUET_TEST_DIR/gitconfig"
+  git config --global duet.stalePolicy warn
+  git config duet.stalePolicy solo
+  run git duet config --list
+  assert_line "local	duet.stalePolicy=solo"
+}
+
+@test "config: environment wins over git config" {
+  git config duet.stalePolicy solo
+  GIT_DUET_STALE_POLICY=prompt run git duet config --list
+  assert_line "env	duet.stalePolicy=prompt"
+}
+
+@test "config: keys without value are true" {
+  printf '[duet]\n\tallowMultipleCommitters\n' >> .git/config
+  run git duet -q jd fb zs
+  run git duet
+  assert_line "GIT_COMMITTER_#2_NAME='Zubaz Shirts'"
+}
+
+@test "config: fails on invalid git config values" {
+  git config duet.stalePolicy never
+  run git duet jd fb
+  assert_failure
+  assert_output 'unknown stale policy never'
+}
@@ -123,7 +123,7 @@ exec git duet-post-commit "$@"' > .git/hooks/post-commit
 @test "requires hook file as argument" {
   run git duet-install-hook -q notAHookFile
   assert_failure
-  assert_line "Usage: git-duet-install-hook [-hq] { pre-commit | prepare-commit-msg | post-commit }"
+  assert_line "Usage: git-duet-install-hook [-hq] [-C path] [--recurse-submodules] { pre-commit | prepare-commit-msg | post-commit }"
 }
 
 @test "writes global prepare-commit-msg hook file if GIT_DUET_GLOBAL is set" {
@@ -157,3 +157,32 @@ exec git duet-post-commit "$@"' > .git/hooks/post-commit
   assert_success
   [ -f ./hooks/post-commit ]
 }
+
+@test "-C writes the hook file of another repository" {
+  cd "$GIT_DUET_TEST_DIR"
+  run git duet-install-hook -q -C repo pre-commit
+  assert_success
+  [ -f repo/.git/hooks/pre-commit ]
+}
+
+@test "writes the hook file of GIT_DIR" {
+  cd "$GIT_DUET_TEST_DIR"
+  GIT_DIR=repo/.git run git duet-install-hook -q pre-commit
+  assert_success
+  [ -f repo/.git/hooks/pre-commit ]
+}
+
+@test "writes the hook file to core.hooksPath" {
+  git config core.hooksPath .githooks
+  run git duet-install-hook -q pre-commit
+  assert_success
+  [ -f .githooks/pre-commit ]
+}
+
+@test "--recurse-submodules writes the hook file of the submodules" {
+  add_submodule lib
+  run git duet-install-hook -q --recurse-submodules pre-commit
+  assert_success
+  [ -f .git/hooks/pre-commit ]
+  [ -f .git/modules/lib/hooks/pre-commit ]
+}
@@ -0,0 +1,80 @@
+#!/usr/bin/env bats
+
+load test_helper
+
+@test "mailmap: maps outdated identities to the authors file" {
+  git solo -q jd
+  add_file
+  git duet-commit -q -m 'with an outdated co-author email
+
+Co-authored-by: Frances Bar <frances@old.example.local>'
+  git commit -q --allow-empty -m 'old name' --author 'Jane D <jane@hamsters.biz.local>'
+
+  run git duet mailmap
+  assert_success
+  assert_line 'git-duet-mailmap: added 2 entries to '"$GIT_DUET_TEST_REPO"'/.mailmap'
+  run cat .mailmap
+  assert_output 'Frances Bar <f.bar@hamster.info.local> Frances Bar <frances@old.example.local>
+Jane Doe <jane@hamsters.biz.local> Jane D <jane@hamsters.biz.local>'
+}
+
+@test "mailmap: keeps existing entries" {
+  echo '# maintained by hand' > .mailmap
+  echo 'Test User <test@example.com> <test@example.com>' >> .mailmap
+  git commit -q --allow-empty -m 'old email' --author 'Jane Doe <jane@old.example.local>'
+
+  run git duet mailmap -q
+  assert_success
+  run cat .mailmap
+  assert_output '# maintained by hand
+Test User <test@example.com> <test@example.com>
+Jane Doe <jane@hamsters.biz.local> Jane Doe <jane@old.example.local>'
+
+  run git duet mailmap
+  assert_success
+  assert_output "git-duet-mailmap: added 0 entries to $GIT_DUET_TEST_REPO/.mailmap"
+}
+
+@test "mailmap: reports unknown identities" {
+  run git duet mailmap -q -o - --report "$GIT_DUET_TEST_DIR/report.txt"
+  assert_success
+  run cat "$GIT_DUET_TEST_DIR/report.txt"
+  assert_output 'identities not in the authors file (review and add them to the authors file or .mailmap):
+  Test User <test@example.com>'
+}
+
+@test "authors import: seeds an authors file from a mailmap" {
+  cat > .mailmap <<EOF
+Jane Doe <jane@example.local> <jane@old.example.local>
+Jane Doe <jane@example.local> Jane D <jd@example.local>
+Frances Bar <frances@example.local> <fb@example.local>
+Jack Dawson <jack@other.local> <dawson@example.local>
+EOF
+
+  run git duet authors import --yes --from-mailmap .mailmap -o -
+  assert_success
+  assert_line 0 '---'
+  assert_equal 'authors:
+  fb: Frances Bar
+  jd: Jack Dawson
+  jdo: Jane Doe
+email:
+  domain: example.local
+email_addresses:
+  fb: frances@example.local
+  jd: jack@other.local
+  jdo: jane@example.local' "$(echo "$output" | tail -n +2)"
+}
+
+@test "authors import: does not overwrite an existing authors file" {
+  echo 'Jane Doe <jane@example.local>' > .mailmap
+  touch .git-authors
+
+  run git duet authors import --yes --from-mailmap .mailmap
+  assert_failure
+  assert_output "$GIT_DUET_TEST_REPO/.git-authors already exists, use --force to overwrite it"
+
+  run git duet authors import --yes --from-mailmap .mailmap --force
+  assert_success
+  assert_output "git-duet-authors: wrote 1 authors to $GIT_DUET_TEST_REPO/.git-authors"
+}
@@ -0,0 +1,105 @@
+#!/usr/bin/env bats
+
+load test_helper
+
+# type_into runs the given command in a pseudo-terminal and types the keys once the
+# picker is up
+type_into() {
+  local keys="$1"
+  shift
+  if ! script -qec true /dev/null >/dev/null 2>&1; then
+    skip "script(1) from util-linux is needed for a pseudo-terminal"
+  fi
+  (sleep 0.5; printf "$keys") | script -qec "$*" /dev/null
+}
+
+@test "pick: prompts for initials without a terminal" {
+  run bash -c "echo 'jd fb' | git duet -i"
+  assert_success
+  assert_line "GIT_COMMITTER_NAME='Frances Bar'"
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'jd'
+}
+
+@test "pick: only looks up the emails of the picked authors" {
+  cat > "$GIT_DUET_TEST_DIR/failing-lookup" <<EOF
+#!/usr/bin/env bash
+echo "\$1" >> "$GIT_DUET_TEST_DIR/lookups"
+[ "\$1" != zs ]
+EOF
+  chmod +x "$GIT_DUET_TEST_DIR/failing-lookup"
+  export GIT_DUET_EMAIL_LOOKUP_COMMAND="$GIT_DUET_TEST_DIR/failing-lookup"
+  run bash -c "echo 'jd fb' | git duet -i 2>/dev/null"
+  assert_success
+  run sort -u "$GIT_DUET_TEST_DIR/lookups"
+  assert_success 'fb
+jd'
+}
+
+@test "pick: prompt accepts names and recent pairings" {
+  git duet -q zs on
+  run bash -c "echo '1 jane' | git duet -i 2>/dev/null"
+  assert_success
+  assert_line "GIT_AUTHOR_NAME='Zubaz Shirts'"
+  assert_line "GIT_COMMITTER_NAME='Oscar'"
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.recent-pairings"
+  assert_success 'zs on jd,zs on'
+}
+
+@test "pick: prompt lists recent pairings first" {
+  git duet -q jd fb
+  git duet -q zs on
+  run bash -c "echo 'jd on' | git duet -i 2>&1 >/dev/null"
+  assert_line 0 'recent pairings:'
+  assert_line 1 '  1) zs on  Zubaz Shirts, Oscar'
+  assert_line 2 '  2) jd fb  Jane Doe, Frances Bar'
+  assert_line 3 'authors:'
+}
+
+@test "pick: prompt asks again after unknown initials" {
+  run bash -c "printf 'jd xx\njd fb\n' | git duet -i"
+  assert_success
+  [[ "$output" == *'unknown initials xx'* ]]
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-initials"
+  assert_success 'fb'
+}
+
+@test "pick: fails when no pairing is picked" {
+  run bash -c "git duet -i < /dev/null"
+  assert_failure
+  assert_line 'no pairing picked'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_failure
+}
+
+@test "pick: filters and selects authors in order in a terminal" {
+  type_into 'fra \x7f\x7f\x7fjane\r' git duet -i
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'fb'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-initials"
+  assert_success 'jd'
+}
+
+@test "pick: offers recent pairings first in a terminal" {
+  git duet -q al on
+  type_into '\r' git duet -i
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'al'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-initials"
+  assert_success 'on'
+}
+
+@test "pick: moves with the arrow keys in a terminal" {
+  type_into '\x1b[B\x1b[B \x1b[A\r' git duet -i
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'jd'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-initials"
+  assert_success 'fb'
+}
+
+@test "pick: cancels with escape in a terminal" {
+  run type_into '\x1b' git duet -i
+  assert_failure
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_failure
+}
@@ -0,0 +1,146 @@
+#!/usr/bin/env bats
+
+load test_helper
+
+@test "pre-commit: passes with fresh settings" {
+  git duet -q jd fb
+  run git duet-pre-commit
+  assert_success
+}
+
+@test "pre-commit: rejects stale settings" {
+  git duet -q jd fb
+  git config "$GIT_DUET_CONFIG_NAMESPACE.mtime" "$(( $(date +%s) - 10))"
+  GIT_DUET_SECONDS_AGO_STALE=9 run git duet-pre-commit
+  assert_failure
+  assert_line "your git duet settings are stale"
+}
+
+@test "pre-commit: exits with 90 on stale settings" {
+  git duet -q jd fb
+  git config "$GIT_DUET_CONFIG_NAMESPACE.mtime" "$(( $(date +%s) - 10))"
+  GIT_DUET_SECONDS_AGO_STALE=9 run git duet-pre-commit
+  assert_equal 90 "$status"
+}
+
+@test "pre-commit: reads the staleness cutoff from git config" {
+  git duet -q jd fb
+  git config "$GIT_DUET_CONFIG_NAMESPACE.mtime" "$(( $(date +%s) - 10))"
+  git config duet.secondsAgoStale 9
+  run git duet-pre-commit
+  assert_failure
+  assert_line "your git duet settings are stale"
+}
+
+@test "pre-commit: environment cutoff takes precedence over git config" {
+  git duet -q jd fb
+  git config "$GIT_DUET_CONFIG_NAMESPACE.mtime" "$(( $(date +%s) - 10))"
+  git config duet.secondsAgoStale 9
+  GIT_DUET_SECONDS_AGO_STALE=60 run git duet-pre-commit
+  assert_success
+}
+
+@test "pre-commit: checks the scope the author is drawn from" {
+  git duet -q jd fb
+  git config "$GIT_DUET_CONFIG_NAMESPACE.mtime" "$(( $(date +%s) - 10))"
+  git duet -g -q al on
+  GIT_DUET_SECONDS_AGO_STALE=9 run git duet-pre-commit
+  assert_failure
+  assert_line "your git duet settings are stale"
+}
+
+@test "pre-commit: warns about stale settings with the warn policy" {
+  git duet -q jd fb
+  git config "$GIT_DUET_CONFIG_NAMESPACE.mtime" "$(( $(date +%s) - 10))"
+  GIT_DUET_SECONDS_AGO_STALE=9 GIT_DUET_STALE_POLICY=warn run git duet-pre-commit
+  assert_success
+  assert_line "warning: your git duet settings are stale"
+}
+
+@test "pre-commit: prompt policy blocks without a terminal" {
+  if ! command -v setsid > /dev/null ; then
+    skip "setsid is needed to detach from the terminal"
+  fi
+
+  git duet -q jd fb
+  git config "$GIT_DUET_CONFIG_NAMESPACE.mtime" "$(( $(date +%s) - 10))"
+  GIT_DUET_SECONDS_AGO_STALE=9 GIT_DUET_STALE_POLICY=prompt run setsid git duet-pre-commit < /dev/null
+  assert_failure
+  assert_line "your git duet settings are stale"
+}
+
+@test "pre-commit: solo policy reverts to the last soloist" {
+  git solo -q al
+  git duet -q jd fb
+  git config "$GIT_DUET_CONFIG_NAMESPACE.mtime" "$(( $(date +%s) - 10))"
+  git config duet.stalePolicy solo
+  GIT_DUET_SECONDS_AGO_STALE=9 run git duet-pre-commit
+  assert_failure
+  assert_line "reverted to solo author Abraham Lincoln <abe@hamster.info.local>, commit again to use them."
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_output "al"
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-initials"
+  assert_output ""
+}
+
+@test "pre-commit: solo policy keeps the current author without a soloist" {
+  git duet -q jd fb
+  git config "$GIT_DUET_CONFIG_NAMESPACE.mtime" "$(( $(date +%s) - 10))"
+  GIT_DUET_SECONDS_AGO_STALE=9 GIT_DUET_STALE_POLICY=solo run git duet-pre-commit
+  assert_failure
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_output "jd"
+  GIT_DUET_SECONDS_AGO_STALE=9 GIT_DUET_STALE_POLICY=solo run git duet-pre-commit
+  assert_success
+}
+
+@test "pre-commit: rejects unknown stale policies" {
+  git duet -q jd fb
+  GIT_DUET_STALE_POLICY=sometimes run git duet-pre-commit
+  assert_failure
+  assert_output "unknown stale policy sometimes"
+}
+
+@test "pre-commit: measures staleness from the last commit if configured" {
+  git duet -q jd fb
+  add_file
+  git duet-commit -q -m 'Testing activity'
+  git config "$GIT_DUET_CONFIG_NAMESPACE.mtime" "$(( $(date +%s) - 10))"
+  GIT_DUET_SECONDS_AGO_STALE=9 GIT_DUET_STALE_SINCE=commit run git duet-pre-commit
+  assert_success
+  GIT_DUET_SECONDS_AGO_STALE=9 run git duet-pre-commit
+  assert_failure
+}
+
+@test "pre-commit: ignores commits made before the pairing was set" {
+  git duet -q jd fb
+  git config "$GIT_DUET_CONFIG_NAMESPACE.activity" "$(( $(date +%s) - 20))"
+  git config "$GIT_DUET_CONFIG_NAMESPACE.mtime" "$(( $(date +%s) - 10))"
+  GIT_DUET_SECONDS_AGO_STALE=9 GIT_DUET_STALE_SINCE=commit run git duet-pre-commit
+  assert_failure
+  assert_line "your git duet settings are stale"
+}
+
+@test "pre-commit: rejects pairings past their expiry" {
+  git duet -q jd fb
+  git config "$GIT_DUET_CONFIG_NAMESPACE.mtime" "$(( $(date +%s) - 2 * 24 * 60 * 60))"
+  git config "$GIT_DUET_CONFIG_NAMESPACE.activity" "$(date +%s)"
+  git config duet.expireAt 00:00
+  GIT_DUET_SECONDS_AGO_STALE=$(( 7 * 24 * 60 * 60 )) GIT_DUET_STALE_SINCE=commit run git duet-pre-commit
+  assert_failure
+  assert_line "your git duet settings are stale"
+}
+
+@test "pre-commit: accepts pairings before their expiry" {
+  git duet -q jd fb
+  git config duet.expireAt 00:00
+  run git duet-pre-commit
+  assert_success
+}
+
+@test "pre-commit: rejects invalid expiry times" {
+  git duet -q jd fb
+  GIT_DUET_EXPIRE_AT=6pm run git duet-pre-commit
+  assert_failure
+  assert_output "invalid time of day 6pm (expected HH:MM)"
+}
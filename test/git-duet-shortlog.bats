@@ -0,0 +1,105 @@
+#!/usr/bin/env bats
+
+load test_helper
+
+@test "shortlog: credits author, committer and co-authors" {
+  git duet -q jd fb
+  add_file first.txt
+  git duet-commit -q -m 'first'
+  git solo -q al
+  add_file second.txt
+  git duet-commit -q -m 'second
+
+Co-authored-by: Zubaz Shirts <z.shirts@pika.info.local>'
+  git solo -q jd
+  add_file third.txt
+  git duet-commit -q -m 'third'
+
+  run git duet shortlog -s -n
+  assert_success
+  assert_output '     2	Jane Doe
+     1	Abraham Lincoln
+     1	Frances Bar
+     1	Test User
+     1	Zubaz Shirts'
+}
+
+@test "shortlog: resolves names and emails through the authors file" {
+  git solo -q al
+  add_file
+  git duet-commit -q -m 'with an outdated co-author email
+
+Co-authored-by: Jane Doe <jane@old.example.local>'
+
+  run git duet shortlog -s -e HEAD~1..HEAD
+  assert_success
+  assert_output '     1	Abraham Lincoln <abe@hamster.info.local>
+     1	Jane Doe <jane@hamsters.biz.local>'
+}
+
+@test "shortlog: lists commit subjects" {
+  git duet -q jd fb
+  add_file first.txt
+  git duet-commit -q -m 'first'
+  add_file second.txt
+  git duet-commit -q -m 'second'
+
+  run git duet shortlog HEAD~2..HEAD
+  assert_success
+  assert_line 0 'Frances Bar (2):'
+  assert_line 1 '      first'
+  assert_line 2 '      second'
+  assert_line 3 'Jane Doe (2):'
+}
+
+@test "blame: annotates lines with everybody involved" {
+  git duet -q jd fb
+  echo "first line" > blamed.txt
+  git add blamed.txt
+  git duet-commit -q -m 'first'
+  git solo -q al
+  echo "second line" >> blamed.txt
+  git add blamed.txt
+  git duet-commit -q -m 'second'
+
+  run git duet blame blamed.txt
+  assert_success
+  assert_line 0 "$(git rev-parse --short=8 HEAD~1) (jd,fb $(date +%Y-%m-%d) 1) first line"
+  assert_line 1 "$(git rev-parse --short=8 HEAD) (al    $(date +%Y-%m-%d) 2) second line"
+}
+
+@test "blame: marks uncommitted lines" {
+  echo "new line" >> foo
+  run git duet blame foo
+  assert_success
+  assert_line 0 "00000000 (Not Committed Yet            1) new line"
+}
+
+@test "shortlog: prefers notes over the commit headers and trailers" {
+  git duet -q jd fb
+  add_file
+  git duet-commit -q -m 'first'
+  git notes --ref duet add -m 'participants:
+- name: Jane Doe
+  email: jane@hamsters.biz.local
+- name: Zubaz Shirts
+  email: z.shirts@pika.info.local' HEAD
+
+  run git duet shortlog -s HEAD~1..HEAD
+  assert_success
+  assert_output '     1	Jane Doe
+     1	Zubaz Shirts'
+}
+
+@test "shortlog: warns about invalid notes and falls back to headers and trailers" {
+  git duet -q jd fb
+  add_file
+  git duet-commit -q -m 'first'
+  git notes --ref duet add -m 'participants: [' HEAD
+
+  run git duet shortlog -s HEAD~1..HEAD
+  assert_success
+  assert_line 0 "warning: ignoring invalid note for $(git rev-parse HEAD): yaml: line 1: did not find expected node content"
+  assert_line '     1	Frances Bar'
+  assert_line '     1	Jane Doe'
+}
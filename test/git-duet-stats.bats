@@ -0,0 +1,116 @@
+#!/usr/bin/env bats
+
+load test_helper
+
+@test "stats: counts commits per pair" {
+  git duet -q jd fb
+  add_file first.txt
+  git duet-commit -q -m 'first'
+  add_file second.txt
+  git duet-commit -q -m 'second'
+
+  run git duet stats
+  assert_success
+  assert_line 0 'PAIR   COMMITS  LAST PAIRED'
+  assert_line 1 "fb jd  2        $(date +%Y-%m-%d)"
+}
+
+@test "stats: counts co-authors from trailers" {
+  git solo -q jd
+  add_file
+  git duet-commit -q -m 'with a co-author
+
+Co-authored-by: Zubaz Shirts <z.shirts@pika.info.local>'
+
+  run git duet stats --format csv
+  assert_success
+  assert_line 0 'first,second,commits,last_paired'
+  assert_line 1 "jd,zs,1,$(date +%Y-%m-%d)"
+}
+
+@test "stats: counts every pair of a mob" {
+  export GIT_DUET_ALLOW_MULTIPLE_COMMITTERS=1
+  git duet -q jd fb zs
+  add_file
+  git duet-commit -q -m 'mobbing'
+
+  run git duet-stats --format csv
+  assert_success
+  assert_line "fb,jd,1,$(date +%Y-%m-%d)"
+  assert_line "fb,zs,1,$(date +%Y-%m-%d)"
+  assert_line "jd,zs,1,$(date +%Y-%m-%d)"
+}
+
+@test "stats: shows identities missing from the authors file by email" {
+  git config user.name 'Someone Else'
+  git config user.email 'someone@else.local'
+  git solo -q jd
+  add_file
+  git duet-commit -q -m 'with a stranger
+
+Co-authored-by: Someone Else <someone@else.local>'
+
+  run git duet stats --format csv
+  assert_success
+  assert_line "jd,someone@else.local,1,$(date +%Y-%m-%d)"
+}
+
+@test "stats: includes pairs that never paired with --all" {
+  git duet -q jd fb
+  add_file
+  git duet-commit -q -m 'first'
+
+  run git duet stats --all --format csv
+  assert_success
+  assert_line 1 'al,fb,0,never'
+  assert_line "fb,jd,1,$(date +%Y-%m-%d)"
+}
+
+@test "stats: outputs json" {
+  git duet -q jd fb
+  add_file
+  git duet-commit -q -m 'first'
+
+  run git duet stats --format json
+  assert_success
+  assert_line '    "commits": 1,'
+}
+
+@test "stats: filters by path" {
+  git duet -q jd fb
+  add_file first.txt
+  git duet-commit -q -m 'first'
+  git duet -q jd al
+  add_file second.txt
+  git duet-commit -q -m 'second'
+
+  run git duet stats --format csv -- second.txt
+  assert_success
+  assert_output "first,second,commits,last_paired
+al,jd,1,$(date +%Y-%m-%d)"
+}
+
+@test "stats: filters by date" {
+  git duet -q jd fb
+  add_file
+  git duet-commit -q -m 'first'
+
+  run git duet stats --format csv --until 2000-01-01
+  assert_success
+  assert_output 'first,second,commits,last_paired'
+}
+
+@test "stats: prefers notes over the commit headers and trailers" {
+  git solo -q jd
+  add_file
+  git duet-commit -q -m 'with a note'
+  git notes --ref duet add -m 'participants:
+- name: Jane Doe
+  email: jane@hamsters.biz.local
+- name: Abraham Lincoln
+  email: abe@hamster.info.local' HEAD
+
+  run git duet stats --format csv
+  assert_success
+  assert_line 1 "al,jd,1,$(date +%Y-%m-%d)"
+}
@@ -0,0 +1,88 @@
+#!/usr/bin/env bats
+
+load test_helper
+
+# make_workspace creates a workspace with a few repositories and changes to it
+make_workspace() {
+  export GIT_CONFIG_GLOBAL="$GIT_DUET_TEST_DIR/gitconfig"
+  mkdir -p "$GIT_DUET_TEST_DIR/workspace"
+  for repo in api web docs; do
+    git init -q "$GIT_DUET_TEST_DIR/workspace/$repo"
+  done
+  cd "$GIT_DUET_TEST_DIR/workspace"
+}
+
+@test "status: shows the pairing of the current repository" {
+  git duet -q jd fb
+  run git duet status
+  assert_success
+  [[ "$output" == *"jd fb"* ]]
+}
+
+@test "status: fails if the current repository is not paired" {
+  run git duet status
+  assert_failure
+  [[ "$output" == *"not paired"* ]]
+}
+
+@test "status --workspace: shows the repositories matching the workspace pairing" {
+  make_workspace
+  git duet -q --workspace . jd fb
+
+  run git duet status --workspace .
+  assert_success
+  assert_line 0 'intended pairing: jd fb'
+  assert_line 1 'api   jd fb  ok'
+  assert_line 2 'docs  jd fb  ok'
+  assert_line 3 'web   jd fb  ok'
+}
+
+@test "status --workspace: shows the repositories that drifted" {
+  make_workspace
+  git duet -q --workspace . jd fb
+  git duet -q -C web jd zs
+  git -C docs config --unset-all "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+
+  run git duet status --workspace .
+  assert_failure
+  assert_line 1 'api   jd fb  ok'
+  assert_line 2 'docs  -      not paired'
+  assert_line 3 'web   jd zs  drifted'
+}
+
+@test "status --workspace: does not take a rotated author for drift" {
+  make_workspace
+  git duet -q --workspace . jd fb
+  git duet -q -C web fb jd
+
+  run git duet status --workspace .
+  assert_success
+  assert_line 3 'web   fb jd  ok'
+}
+
+@test "status --workspace: compares with the pairing given" {
+  make_workspace
+  git duet -q --workspace . jd fb
+
+  run git duet status --workspace . jd zs
+  assert_failure
+  assert_line 0 'intended pairing: jd zs'
+  assert_line 1 'api   jd fb  drifted'
+}
+
+@test "status: shows the submodules paired like the superproject" {
+  add_submodule lib
+  git duet -q --recurse-submodules jd fb
+  run git duet status
+  assert_success
+  [[ "${lines[1]}" == *"/lib  jd fb  ok" ]]
+}
+
+@test "status: shows the submodules whose pairing differs from the superproject" {
+  add_submodule lib
+  git duet -q --recurse-submodules jd fb
+  git duet -q al on
+  run git duet status
+  assert_failure
+  [[ "${lines[1]}" == *"/lib  jd fb  drifted" ]]
+}
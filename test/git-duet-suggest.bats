@@ -0,0 +1,72 @@
+#!/usr/bin/env bats
+
+load test_helper
+
+commit_as() {
+  git duet -q "$@"
+  add_file "$(echo "$@" | tr ' ' '-').txt"
+  git duet-commit -q -m "$*"
+}
+
+@test "suggest: prefers pairs who never paired" {
+  commit_as jd fb
+  commit_as al on
+
+  run git duet suggest jd fb al on
+  assert_success
+  assert_output 'git duet jd al
+git duet fb on'
+}
+
+@test "suggest: prefers pairs who paired least recently" {
+  commit_as jd fb
+  GIT_COMMITTER_DATE='2000-01-01T00:00:00' git commit -q --amend --no-edit
+  commit_as al on
+  GIT_COMMITTER_DATE='2000-01-01T00:00:00' git commit -q --amend --no-edit
+  commit_as jd al
+  commit_as jd on
+  commit_as fb al
+  commit_as fb on
+
+  run git duet suggest jd fb al on
+  assert_success
+  assert_output 'git duet jd fb
+git duet al on'
+}
+
+@test "suggest: expands teams from the authors file" {
+  cat >> "$GIT_DUET_AUTHORS_FILE" <<EOF
+teams:
+  zubaz: [zp, zs]
+EOF
+
+  run git duet suggest jd zubaz
+  assert_success
+  assert_output 'git duet jd zp zs'
+}
+
+@test "suggest: respects pairs that must not pair" {
+  run git duet suggest --never jd:fb jd fb al on
+  assert_success
+  assert_output 'git duet jd al
+git duet fb on'
+}
+
+@test "suggest: puts a senior in every pair" {
+  run git duet suggest --seniors al,fb jd fb al on
+  assert_success
+  assert_output 'git duet jd fb
+git duet al on'
+}
+
+@test "suggest: fails if the constraints can't be met" {
+  run git duet suggest --seniors al jd fb al on
+  assert_failure
+  assert_output 'no pairing satisfies the constraints'
+}
+
+@test "suggest: fails on unknown initials" {
+  run git duet suggest jd xx
+  assert_failure
+  assert_output 'unknown initials xx'
+}
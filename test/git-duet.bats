@@ -20,6 +20,62 @@ load test_helper
   assert_success 'jd'
 }
 
+@test "resolves unambiguous name fragments" {
+  git duet -q jane fran
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'jd'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-initials"
+  assert_success 'fb'
+}
+
+@test "resolves name fragments by username" {
+  git duet -q jd abe
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-initials"
+  assert_success 'al'
+}
+
+@test "fails with exit code 86 on unknown initials" {
+  run git duet jd xx
+  assert_equal 86 "$status"
+  assert_output 'unknown initials xx'
+}
+
+@test "suggests the closest initials" {
+  run git duet dj fb
+  assert_equal 86 "$status"
+  assert_output 'unknown initials dj, did you mean jd (Jane Doe)?'
+}
+
+@test "suggests authors with similar names" {
+  run git duet jd fracnes
+  assert_equal 86 "$status"
+  assert_output 'unknown initials fracnes, did you mean fb (Frances Bar)?'
+}
+
+@test "fails with exit code 88 on a missing authors file" {
+  rm "$GIT_DUET_AUTHORS_FILE"
+  run git duet jd fb
+  assert_equal 88 "$status"
+}
+
+@test "fails with exit code 88 on an invalid authors file" {
+  echo "pairs: [" > "$GIT_DUET_AUTHORS_FILE"
+  run git duet jd fb
+  assert_equal 88 "$status"
+  [[ "$output" == "could not parse $GIT_DUET_AUTHORS_FILE"* ]]
+}
+
+@test "fails with exit code 91 on invalid configuration" {
+  GIT_DUET_ROTATION_COMMITS=many run git duet jd fb
+  assert_equal 91 "$status"
+}
+
+@test "fails with exit code 87 on ambiguous name fragments" {
+  run git duet jd zubaz
+  assert_equal 87 "$status"
+  assert_output 'ambiguous initials zubaz, could be zp (Zubaz Pants), zs (Zubaz Shirts)'
+}
+
 @test "sets the git user initials" {
   git duet -q jd fb
   run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
@@ -68,6 +124,241 @@ load test_helper
   assert_success 'fb9000@dalek.info.local'
 }
 
+write_json_lookup() {
+  cat > "$GIT_DUET_TEST_DIR/json-lookup" <<EOF
+#!/usr/bin/env bash
+case "\$(cat)" in
+*'"initials":"jd"'*) echo '{"email":"jane@json.local","name":"Jane Q. Doe","signing_key":"0xJANE"}' ;;
+*'"initials":"xx"'*) echo '{"email":"xavier@json.local","name":"Xavier Xu"}' ;;
+esac
+EOF
+  chmod +x "$GIT_DUET_TEST_DIR/json-lookup"
+  export GIT_DUET_EMAIL_LOOKUP_COMMAND="$GIT_DUET_TEST_DIR/json-lookup"
+  export GIT_DUET_EMAIL_LOOKUP_PROTOCOL=2
+}
+
+@test "looks up names and emails with the JSON lookup protocol" {
+  write_json_lookup
+  git duet -q jd fb
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-name"
+  assert_success 'Jane Q. Doe'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-email"
+  assert_success 'jane@json.local'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-email"
+  assert_success 'f.bar@hamster.info.local'
+}
+
+@test "keeps the signing keys from the JSON lookup protocol" {
+  write_json_lookup
+  git duet -q fb jd
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-signingkey"
+  assert_success '0xJANE'
+  git duet -q fb zs
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-signingkey"
+  assert_failure
+}
+
+@test "commits signed with the signing key from the JSON lookup protocol" {
+  write_json_lookup
+  cat > "$GIT_DUET_TEST_DIR/fake-gpg" <<EOF
+#!/usr/bin/env bash
+echo "\$@" > "$GIT_DUET_TEST_DIR/gpg-args"
+cat > /dev/null
+echo '[GNUPG:] SIG_CREATED D 1 8 00 1 0' >&2
+printf -- '-----BEGIN PGP SIGNATURE-----\n\nfake\n-----END PGP SIGNATURE-----\n'
+EOF
+  chmod +x "$GIT_DUET_TEST_DIR/fake-gpg"
+  git config gpg.program "$GIT_DUET_TEST_DIR/fake-gpg"
+  git solo -q jd
+  add_file
+  run git duet-commit -q -S -m 'Signed'
+  assert_success
+  grep -- '0xJANE' "$GIT_DUET_TEST_DIR/gpg-args"
+}
+
+@test "resolves initials missing from the authors file with the JSON lookup protocol" {
+  write_json_lookup
+  git duet -q jd xx
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-name"
+  assert_success 'Xavier Xu'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-email"
+  assert_success 'xavier@json.local'
+}
+
+@test "leaves initials unknown to the JSON lookup unknown" {
+  write_json_lookup
+  run git duet -q jd yy
+  assert_equal 86 "$status"
+  assert_output 'unknown initials yy'
+}
+
+@test "rejects unknown email lookup protocols" {
+  GIT_DUET_EMAIL_LOOKUP_PROTOCOL=3 run git duet -q jd fb
+  assert_failure
+  assert_output 'unknown email lookup protocol 3 (expected 1 or 2)'
+}
+
+@test "kills email lookups exceeding the timeout" {
+  printf '#!/usr/bin/env bash\nexec sleep 5\n' > "$GIT_DUET_TEST_DIR/slow-lookup"
+  chmod +x "$GIT_DUET_TEST_DIR/slow-lookup"
+  GIT_DUET_EMAIL_LOOKUP_COMMAND="$GIT_DUET_TEST_DIR/slow-lookup" GIT_DUET_EMAIL_LOOKUP_TIMEOUT=100ms run git duet -q jd fb
+  assert_failure
+  assert_output 'email lookup for jd timed out after 100ms'
+}
+
+@test "kills the processes started by email lookups exceeding the timeout" {
+  printf '#!/usr/bin/env bash\nsleep 3\necho x@y\n' > "$GIT_DUET_TEST_DIR/slow-lookup"
+  chmod +x "$GIT_DUET_TEST_DIR/slow-lookup"
+  SECONDS=0
+  GIT_DUET_EMAIL_LOOKUP_COMMAND="$GIT_DUET_TEST_DIR/slow-lookup" GIT_DUET_EMAIL_LOOKUP_TIMEOUT=100ms run git duet -q jd fb
+  [ "$SECONDS" -lt 2 ]
+  assert_failure
+  assert_output 'email lookup for jd timed out after 100ms'
+}
+
+@test "falls back to the authors file when the email lookup fails" {
+  printf '#!/usr/bin/env bash\nexit 1\n' > "$GIT_DUET_TEST_DIR/failing-lookup"
+  chmod +x "$GIT_DUET_TEST_DIR/failing-lookup"
+  GIT_DUET_EMAIL_LOOKUP_COMMAND="$GIT_DUET_TEST_DIR/failing-lookup" GIT_DUET_EMAIL_LOOKUP_FALLBACK=1 git duet -q jd fb
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-email"
+  assert_success 'jane@hamsters.biz.local'
+}
+
+write_counting_lookup() {
+  cat > "$GIT_DUET_TEST_DIR/counting-lookup" <<EOF
+#!/usr/bin/env bash
+echo "\$1" >> "$GIT_DUET_TEST_DIR/lookups"
+if [ "\$1" = jd ]; then
+  echo jane_doe@lookie.me.local
+fi
+EOF
+  chmod +x "$GIT_DUET_TEST_DIR/counting-lookup"
+  export GIT_DUET_EMAIL_LOOKUP_COMMAND="$GIT_DUET_TEST_DIR/counting-lookup"
+}
+
+@test "caches email lookups for the TTL" {
+  write_counting_lookup
+  GIT_DUET_EMAIL_LOOKUP_CACHE_TTL=1h git duet -q jd fb
+  GIT_DUET_EMAIL_LOOKUP_CACHE_TTL=1h git duet -q jd fb
+  run cat "$GIT_DUET_TEST_DIR/lookups"
+  assert_success 'jd
+fb
+fb'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-email"
+  assert_success 'jane_doe@lookie.me.local'
+}
+
+@test "caches empty email lookups for the negative TTL" {
+  write_counting_lookup
+  GIT_DUET_EMAIL_LOOKUP_NEGATIVE_TTL=1h git duet -q jd fb
+  GIT_DUET_EMAIL_LOOKUP_NEGATIVE_TTL=1h git duet -q jd fb
+  run cat "$GIT_DUET_TEST_DIR/lookups"
+  assert_success 'jd
+fb
+jd'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-email"
+  assert_success 'f.bar@hamster.info.local'
+}
+
+@test "runs cached email lookups again with --refresh" {
+  write_counting_lookup
+  export GIT_DUET_EMAIL_LOOKUP_CACHE_TTL=1h GIT_DUET_EMAIL_LOOKUP_NEGATIVE_TTL=1h
+  git duet -q jd fb
+  git duet -q --refresh jd fb
+  git duet -q jd fb
+  run cat "$GIT_DUET_TEST_DIR/lookups"
+  assert_success 'jd
+fb
+jd
+fb'
+}
+
+@test "forgets cached email lookups with git duet cache clear" {
+  write_counting_lookup
+  export GIT_DUET_EMAIL_LOOKUP_CACHE_TTL=1h GIT_DUET_EMAIL_LOOKUP_NEGATIVE_TTL=1h
+  git duet -q jd fb
+  run git duet cache clear
+  assert_success ''
+  [ ! -e "$XDG_CACHE_HOME/git-duet" ]
+  git duet -q jd fb
+  run cat "$GIT_DUET_TEST_DIR/lookups"
+  assert_success 'jd
+fb
+jd
+fb'
+}
+
+@test "fails when the email lookup fails without fallback" {
+  printf '#!/usr/bin/env bash\nexit 1\n' > "$GIT_DUET_TEST_DIR/failing-lookup"
+  chmod +x "$GIT_DUET_TEST_DIR/failing-lookup"
+  GIT_DUET_EMAIL_LOOKUP_COMMAND="$GIT_DUET_TEST_DIR/failing-lookup" run git duet -q jd fb
+  assert_equal 86 "$status"
+  assert_output 'exit status 1'
+}
+
+@test "looks up emails in the LDAP directory" {
+  start_ldap_server
+  add_ldap_config '(uid={{.Username}})'
+  git duet -q al jd
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-email"
+  assert_success 'abe@ldap.example.com'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-email"
+  assert_success 'jane@hamsters.biz.local'
+}
+
+@test "maps LDAP attributes and resolves initials missing from the authors file" {
+  start_ldap_server
+  add_ldap_config '(initials={{.Initials}})' 'attributes:' '  email: mail' '  name: cn'
+  git duet -q jd xx
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-name"
+  assert_success 'Jane Directory Doe'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-name"
+  assert_success 'Xavier Xu'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-email"
+  assert_success 'xavier@ldap.example.com'
+}
+
+@test "binds to the LDAP directory with the password from the environment" {
+  start_ldap_server
+  add_ldap_config '(initials={{.Initials}})' 'bind_dn: cn=git-duet,dc=example,dc=com' 'bind_password_env: GIT_DUET_TEST_LDAP_PASSWORD'
+  GIT_DUET_TEST_LDAP_PASSWORD=secret git duet -q al jd
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-email"
+  assert_success 'abe@ldap.example.com'
+
+  GIT_DUET_TEST_LDAP_PASSWORD=wrong run git duet -q al jd
+  assert_equal 86 "$status"
+  assert_output 'ldap lookup for al failed: ldap result code 49'
+}
+
+@test "falls back from a failing LDAP directory with GIT_DUET_EMAIL_LOOKUP_FALLBACK" {
+  start_ldap_server
+  add_ldap_config '(initials={{.Initials}})' 'bind_dn: cn=git-duet,dc=example,dc=com' 'bind_password_env: GIT_DUET_TEST_LDAP_PASSWORD'
+  GIT_DUET_TEST_LDAP_PASSWORD=wrong GIT_DUET_EMAIL_LOOKUP_FALLBACK=1 run git duet -q al jd
+  assert_success
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-email"
+  assert_success 'abe@hamster.info.local'
+}
+
+@test "times out LDAP lookups" {
+  start_ldap_server -delay 5s
+  add_ldap_config '(initials={{.Initials}})'
+  GIT_DUET_EMAIL_LOOKUP_TIMEOUT=200ms run git duet -q al jd
+  assert_equal 86 "$status"
+  assert_output 'ldap lookup for al timed out after 200ms'
+}
+
+@test "caches LDAP lookups" {
+  start_ldap_server
+  add_ldap_config '(initials={{.Initials}})'
+  export GIT_DUET_EMAIL_LOOKUP_CACHE_TTL=1h
+  git duet -q al jd
+  kill "$(cat "$GIT_DUET_TEST_DIR/ldap-server.pid")"
+  rm "$GIT_DUET_TEST_DIR/ldap-server.pid"
+  git duet -q jd al
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-email"
+  assert_success 'jane.doe@ldap.example.com'
+}
+
 @test "uses custom email template for author when provided" {
   local suffix=$RANDOM
 
@@ -251,6 +542,20 @@ load test_helper
   [ -f .git/hooks/prepare-commit-msg ]
 }
 
+@test "installs post-commit hook file writing the note if GIT_DUET_CO_AUTHORED_BY and GIT_DUET_NOTES" {
+  export GIT_DUET_CO_AUTHORED_BY=1
+  export GIT_DUET_NOTES=1
+  git duet -q jd fb
+  [ -f .git/hooks/post-commit ]
+
+  add_file
+  git commit -q -m 'Testing the note from the hook'
+  run git notes --ref duet show HEAD
+  assert_success
+  assert_line 1 '- initials: jd'
+  assert_line '- initials: fb'
+}
+
 @test "without args installs the hook and sets git user.name and user.email if GIT_DUET_CO_AUTHORED_BY" {
   git duet -q jd fb
   run git config "user.name"
@@ -521,3 +826,161 @@ load test_helper
   GIT_DUET_CO_AUTHORED_BY=1 run git duet
   assert_equal 0 $status
 }
+
+@test "-C sets the pairing of another repository" {
+  cd "$GIT_DUET_TEST_DIR"
+  git duet -q -C repo jd fb
+  run git -C repo config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'jd'
+  run git -C repo config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-initials"
+  assert_success 'fb'
+}
+
+@test "-C applies to subcommands" {
+  cd "$GIT_DUET_TEST_DIR"
+  git -C repo config duet.stalePolicy warn
+  run git duet -C repo config --list
+  assert_success
+  assert_line "local	duet.stalePolicy=warn"
+}
+
+@test "-C reads the authors file of the other repository" {
+  unset GIT_DUET_AUTHORS_FILE
+  printf 'authors:\n  jd: Jane Other\n  fb: Frances Other\nemail:\n  domain: other.local\n' > .git-authors
+  cd "$GIT_DUET_TEST_DIR"
+  git duet -q -C repo jd fb
+  run git -C repo config "$GIT_DUET_CONFIG_NAMESPACE.git-author-name"
+  assert_success 'Jane Other'
+}
+
+@test "fails for a -C path that does not exist" {
+  run git duet -C "$GIT_DUET_TEST_DIR/missing" jd fb
+  assert_failure
+  [[ "$output" == *missing* ]]
+}
+
+@test "follows GIT_DIR and GIT_WORK_TREE" {
+  cd "$GIT_DUET_TEST_DIR"
+  GIT_DIR=repo/.git GIT_WORK_TREE=repo git duet -q jd fb
+  run git -C repo config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'jd'
+}
+
+@test "sets the pairing of a bare repository" {
+  git init -q --bare "$GIT_DUET_TEST_DIR/bare.git"
+  cd "$GIT_DUET_TEST_DIR/bare.git"
+  git duet -q jd fb
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'jd'
+}
+
+# make_workspace creates a workspace directory with the repositories given
+make_workspace() {
+  export GIT_CONFIG_GLOBAL="$GIT_DUET_TEST_DIR/gitconfig"
+  mkdir -p "$GIT_DUET_TEST_DIR/workspace"
+  for repo in "$@"; do
+    git init -q "$GIT_DUET_TEST_DIR/workspace/$repo"
+  done
+}
+
+@test "--workspace sets the pairing in every repository of a directory" {
+  make_workspace api web
+  mkdir "$GIT_DUET_TEST_DIR/workspace/notes"
+
+  run git duet --workspace "$GIT_DUET_TEST_DIR/workspace" jd fb
+  assert_success
+  assert_line 0 'api: ok'
+  assert_line 1 'web: ok'
+  for repo in api web; do
+    run git -C "$GIT_DUET_TEST_DIR/workspace/$repo" config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+    assert_success 'jd'
+    run git -C "$GIT_DUET_TEST_DIR/workspace/$repo" config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-initials"
+    assert_success 'fb'
+  done
+}
+
+@test "--workspace reads the repositories from a manifest" {
+  make_workspace api web
+  cat > "$GIT_DUET_TEST_DIR/workspace/.git-duet-workspace" <<EOF
+# only the api for now
+api
+../repo
+EOF
+
+  run git duet -q --workspace "$GIT_DUET_TEST_DIR/workspace" jd fb
+  assert_success ''
+  run git -C "$GIT_DUET_TEST_DIR/workspace/api" config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'jd'
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'jd'
+  run git -C "$GIT_DUET_TEST_DIR/workspace/web" config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_failure
+}
+
+@test "--workspace reports the repositories it failed for" {
+  make_workspace api
+  printf 'api\nmissing\n' > "$GIT_DUET_TEST_DIR/workspace/.git-duet-workspace"
+
+  run git duet --workspace "$GIT_DUET_TEST_DIR/workspace" jd fb
+  assert_failure
+  assert_line 0 'api: ok'
+  [[ "${lines[1]}" == missing:* ]]
+}
+
+@test "--workspace installs the prepare-commit-msg hook if GIT_DUET_CO_AUTHORED_BY is set" {
+  make_workspace api web
+
+  GIT_DUET_CO_AUTHORED_BY=1 git duet -q --workspace "$GIT_DUET_TEST_DIR/workspace" jd fb
+  [ -f "$GIT_DUET_TEST_DIR/workspace/api/.git/hooks/prepare-commit-msg" ]
+  [ -f "$GIT_DUET_TEST_DIR/workspace/web/.git/hooks/prepare-commit-msg" ]
+}
+
+@test "--workspace installs the hooks given with --install-hooks" {
+  make_workspace api web
+
+  git duet -q --workspace "$GIT_DUET_TEST_DIR/workspace" --install-hooks pre-commit,post-commit jd fb
+  for repo in api web; do
+    [ -f "$GIT_DUET_TEST_DIR/workspace/$repo/.git/hooks/pre-commit" ]
+    [ -f "$GIT_DUET_TEST_DIR/workspace/$repo/.git/hooks/post-commit" ]
+    [ ! -f "$GIT_DUET_TEST_DIR/workspace/$repo/.git/hooks/prepare-commit-msg" ]
+  done
+}
+
+@test "--install-hooks installs the hooks in the repository" {
+  git duet -q --install-hooks pre-commit jd fb
+  [ -f .git/hooks/pre-commit ]
+}
+
+@test "--install-hooks refuses unknown hooks" {
+  run git duet -q --install-hooks pre-push jd fb
+  assert_equal 1 "$status"
+  assert_output 'unknown hook pre-push (expected pre-commit, prepare-commit-msg, post-commit)'
+}
+
+@test "--recurse-submodules sets the pairing in the submodules" {
+  add_submodule lib
+  git duet -q --recurse-submodules jd fb
+  run git -C lib config --local "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'jd'
+  run git -C lib config --local "$GIT_DUET_CONFIG_NAMESPACE.git-committer-initials"
+  assert_success 'fb'
+}
+
+@test "--recurse-submodules installs the hooks in the submodules with GIT_DUET_CO_AUTHORED_BY" {
+  add_submodule lib
+  GIT_DUET_CO_AUTHORED_BY=1 git duet -q --recurse-submodules jd fb
+  [ -f .git/modules/lib/hooks/prepare-commit-msg ]
+}
+
+@test "--recurse-submodules installs the hooks in the submodules of a global pairing" {
+  add_submodule lib
+  GIT_DUET_GLOBAL=1 GIT_DUET_CO_AUTHORED_BY=1 git duet -q --recurse-submodules jd fb
+  [ -f .git/modules/lib/hooks/prepare-commit-msg ]
+}
+
+@test "leaves the submodules alone without --recurse-submodules" {
+  add_submodule lib
+  git duet -q jd fb
+  run git -C lib config --local "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_failure
+}
@@ -216,4 +216,41 @@ load test_helper
   assert_failure
   run git config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-email"
   assert_success ""
-}
\ No newline at end of file
+}
+
+@test "resolves name fragments" {
+  git solo -q oscar
+  run git config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'on'
+}
+
+@test "fails with exit code 86 on unknown initials" {
+  run git solo jx
+  assert_equal 86 "$status"
+  assert_output 'unknown initials jx, did you mean jd (Jane Doe)?'
+}
+
+@test "-C sets the soloist of another repository" {
+  cd "$GIT_DUET_TEST_DIR"
+  git solo -q -C repo jd
+  run git -C repo config "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'jd'
+}
+
+@test "When GIT_DUET_DEFAULT_UPDATE is set, git-solo with no args follows GIT_DIR" {
+  git duet jd fb
+  cd "$GIT_DUET_TEST_DIR"
+  GIT_DIR=repo/.git GIT_DUET_DEFAULT_UPDATE=1 git solo
+  run git -C repo config "$GIT_DUET_CONFIG_NAMESPACE.git-author-email"
+  assert_failure
+}
+
+@test "--recurse-submodules sets the soloist in the submodules" {
+  add_submodule lib
+  git -C lib config "$GIT_DUET_CONFIG_NAMESPACE.git-committer-initials" fb
+  git solo -q --recurse-submodules jd
+  run git -C lib config --local "$GIT_DUET_CONFIG_NAMESPACE.git-author-initials"
+  assert_success 'jd'
+  run git -C lib config --local "$GIT_DUET_CONFIG_NAMESPACE.git-committer-initials"
+  assert_success ''
+}
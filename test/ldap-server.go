@@ -0,0 +1,186 @@
+//go:build ignore
+// +build ignore
+
+// ldap-server is a stand-in LDAP directory for the tests, serving a few
+// people on a local port written to the given file:
+//
+//	go run test/ldap-server.go [-delay 5s] <port file>
+//
+// Binding as cn=git-duet,dc=example,dc=com takes the password "secret",
+// anonymous binds are allowed
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/git-duet/git-duet/internal/ldap"
+)
+
+const (
+	bindDN   = "cn=git-duet,dc=example,dc=com"
+	password = "secret"
+)
+
+type entry struct {
+	dn         string
+	attributes map[string][]string
+}
+
+var directory = []entry{
+	{"uid=jane,ou=people,dc=example,dc=com", map[string][]string{
+		"uid": {"jane"}, "initials": {"jd"}, "cn": {"Jane Directory Doe"}, "mail": {"jane.doe@ldap.example.com"},
+	}},
+	{"uid=abe,ou=people,dc=example,dc=com", map[string][]string{
+		"uid": {"abe"}, "initials": {"al"}, "cn": {"Abraham Lincoln"}, "mail": {"abe@ldap.example.com"},
+	}},
+	{"uid=xavier,ou=people,dc=example,dc=com", map[string][]string{
+		"uid": {"xavier"}, "initials": {"xx"}, "cn": {"Xavier Xu"}, "mail": {"xavier@ldap.example.com"},
+	}},
+}
+
+func main() {
+	delay := flag.Duration("delay", 0, "Wait before answering searches")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		log.Fatal("usage: ldap-server [-delay <duration>] <port file>")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	port := fmt.Sprint(listener.Addr().(*net.TCPAddr).Port)
+	if err = ioutil.WriteFile(flag.Arg(0)+".tmp", []byte(port), 0644); err != nil {
+		log.Fatal(err)
+	}
+	if err = os.Rename(flag.Arg(0)+".tmp", flag.Arg(0)); err != nil {
+		log.Fatal(err)
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Fatal(err)
+		}
+		go serve(conn, *delay)
+	}
+}
+
+func serve(conn net.Conn, delay time.Duration) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	for {
+		message, err := ldap.ReadPacket(reader)
+		if err != nil || len(message.Children) < 2 {
+			return
+		}
+		id, _ := message.Children[0].Int()
+		op := message.Children[1]
+		reply := func(op *ldap.Packet) {
+			conn.Write(ldap.Sequence(ldap.Integer(id), op).Bytes())
+		}
+
+		switch op.Tag {
+		case ldap.OpBindRequest:
+			code := int64(0)
+			if dn := op.Child(1).String(); dn != "" && (dn != bindDN || op.Child(2).String() != password) {
+				code = 49 // invalid credentials
+			}
+			reply(ldap.Application(ldap.OpBindResponse, ldap.Enumerated(code), ldap.OctetString(""), ldap.OctetString("")))
+		case ldap.OpSearchRequest:
+			time.Sleep(delay)
+			for _, e := range directory {
+				if !strings.HasSuffix(e.dn, op.Child(0).String()) || !matches(op.Child(6), e) {
+					continue
+				}
+				var attributes []*ldap.Packet
+				for _, requested := range op.Child(7).Children {
+					var values []*ldap.Packet
+					for _, value := range e.attributes[requested.String()] {
+						values = append(values, ldap.OctetString(value))
+					}
+					attributes = append(attributes, ldap.Sequence(requested, &ldap.Packet{
+						Constructed: true, Tag: ldap.TagSet, Children: values,
+					}))
+				}
+				reply(ldap.Application(ldap.OpSearchResultEntry, ldap.OctetString(e.dn), ldap.Sequence(attributes...)))
+			}
+			reply(ldap.Application(ldap.OpSearchResultDone, ldap.Enumerated(0), ldap.OctetString(""), ldap.OctetString("")))
+		case ldap.OpUnbindRequest:
+			return
+		}
+	}
+}
+
+// matches evaluates the and, or, not, equality, present and substrings
+// filters against the entry
+func matches(filter *ldap.Packet, e entry) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matches(child, e) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matches(child, e) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !matches(filter.Child(0), e)
+	case ldap.FilterPresent:
+		return len(e.attributes[filter.String()]) > 0
+	case ldap.FilterEquality:
+		for _, value := range e.attributes[filter.Child(0).String()] {
+			if strings.EqualFold(value, filter.Child(1).String()) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterSubstrings:
+		for _, value := range e.attributes[filter.Child(0).String()] {
+			if matchesSubstrings(strings.ToLower(value), filter.Child(1)) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func matchesSubstrings(value string, substrings *ldap.Packet) bool {
+	for _, part := range substrings.Children {
+		s := strings.ToLower(part.String())
+		switch part.Tag {
+		case ldap.SubstringInitial:
+			if !strings.HasPrefix(value, s) {
+				return false
+			}
+			value = value[len(s):]
+		case ldap.SubstringAny:
+			i := strings.Index(value, s)
+			if i < 0 {
+				return false
+			}
+			value = value[i+len(s):]
+		case ldap.SubstringFinal:
+			if !strings.HasSuffix(value, s) {
+				return false
+			}
+		}
+	}
+	return true
+}
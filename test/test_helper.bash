@@ -16,6 +16,7 @@ setup() {
   export GIT_DUET_CONFIG_NAMESPACE='foo.bar'
   export GIT_DUET_AUTHORS_FILE="${GIT_DUET_TEST_DIR}/.git-authors"
   export GIT_DUET_TEST_LOOKUP="${GIT_DUET_TEST_DIR}/email-lookup"
+  export XDG_CACHE_HOME="${GIT_DUET_TEST_DIR}/cache"
   export GIT_DUET_TEST_REPO="${GIT_DUET_TEST_DIR}/repo"
 
   cat > "$GIT_DUET_AUTHORS_FILE" <<EOF
@@ -53,6 +54,10 @@ EOF
 }
 
 teardown() {
+  if [ -f "$GIT_DUET_TEST_DIR/ldap-server.pid" ]; then
+    kill "$(cat "$GIT_DUET_TEST_DIR/ldap-server.pid")" || true
+  fi
+
   git config --global --remove-section $GIT_DUET_CONFIG_NAMESPACE || true
   git config --global --unset init.templateDir || true
 
@@ -74,6 +79,35 @@ teardown() {
   rm -rf "$GIT_DUET_TEST_DIR"
 }
 
+# start_ldap_server runs the stand-in directory of test/ldap-server.go with the
+# given options, exporting its URL as GIT_DUET_TEST_LDAP
+start_ldap_server() {
+  (cd "$BATS_TEST_DIRNAME/.." && go build -o "$GIT_DUET_TEST_DIR/ldap-server" ./test/ldap-server.go)
+  "$GIT_DUET_TEST_DIR/ldap-server" "$@" "$GIT_DUET_TEST_DIR/ldap-port" >/dev/null 2>&1 3>&- &
+  echo $! > "$GIT_DUET_TEST_DIR/ldap-server.pid"
+  for i in $(seq 50); do
+    [ -f "$GIT_DUET_TEST_DIR/ldap-port" ] && break
+    sleep 0.1
+  done
+  export GIT_DUET_TEST_LDAP="ldap://127.0.0.1:$(cat "$GIT_DUET_TEST_DIR/ldap-port")"
+}
+
+# add_ldap_config appends an ldap section searching the stand-in directory
+# with the given filter, followed by any further lines of the section
+add_ldap_config() {
+  local filter="$1"
+  shift
+  {
+    echo 'ldap:'
+    echo "  url: $GIT_DUET_TEST_LDAP"
+    echo '  base_dn: ou=people,dc=example,dc=com'
+    echo "  filter: '$filter'"
+    for line in "$@"; do
+      echo "  $line"
+    done
+  } >> "$GIT_DUET_AUTHORS_FILE"
+}
+
 add_file() {
   if [ $# -eq 0 ]; then
     touch file.txt
@@ -118,6 +152,15 @@ email_addresses:
 EOF
 }
 
+# add_submodule adds an initialized submodule with a commit to the test
+# repository, at the given path
+add_submodule() {
+  local origin="$GIT_DUET_TEST_DIR/$(basename "$1")-origin"
+  git init -q "$origin"
+  git -C "$origin" -c user.name='Test User' -c user.email=test@example.com commit -q --allow-empty -m 'submodule commit'
+  git -c protocol.file.allow=always submodule -q add "$origin" "$1"
+}
+
 assert_head_is_merge () {
     msha=$(git rev-list --merges HEAD~1..HEAD)
     [ -z "$msha" ] && return 1
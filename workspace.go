@@ -0,0 +1,156 @@
+package duet
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WorkspaceManifest is the file listing the repositories of a workspace
+// directory, one path per line relative to the directory (blank lines and
+// lines starting with # are ignored)
+const WorkspaceManifest = ".git-duet-workspace"
+
+// Workspace is a set of repositories paired on together
+// Path is the workspace directory or manifest file it was loaded from, Repos
+// the absolute paths of the repositories
+type Workspace struct {
+	Path  string
+	Repos []string
+}
+
+// LoadWorkspace reads the workspace at path: a manifest file, a directory with
+// a manifest, or a directory whose subdirectories are the repositories
+func LoadWorkspace(path string) (workspace *Workspace, err error) {
+	if path, err = filepath.Abs(path); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace = &Workspace{Path: path}
+	manifest := path
+	if info.IsDir() {
+		manifest = filepath.Join(path, WorkspaceManifest)
+		if _, err := os.Stat(manifest); os.IsNotExist(err) {
+			workspace.Repos, err = findRepos(path)
+			return workspace, err
+		}
+	}
+
+	workspace.Repos, err = readManifest(manifest)
+	return workspace, err
+}
+
+// Rel returns the path of a repository relative to the workspace, for
+// reporting
+func (w *Workspace) Rel(repo string) string {
+	dir := w.Path
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	if rel, err := filepath.Rel(dir, repo); err == nil {
+		return rel
+	}
+	return repo
+}
+
+func readManifest(manifest string) (repos []string, err error) {
+	file, err := os.Open(manifest)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = expandHome(line, os.Getenv)
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(filepath.Dir(manifest), line)
+		}
+		repos = append(repos, filepath.Clean(line))
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("no repositories listed in %s", manifest)
+	}
+	return repos, nil
+}
+
+// findRepos returns the subdirectories of dir with a .git directory (or file,
+// for worktrees and submodules)
+func findRepos(dir string) (repos []string, err error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		repo := filepath.Join(dir, entry.Name())
+		if _, err := os.Stat(filepath.Join(repo, ".git")); err == nil {
+			repos = append(repos, repo)
+		}
+	}
+	sort.Strings(repos)
+
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("no repositories in %s (and no %s)", dir, WorkspaceManifest)
+	}
+	return repos, nil
+}
+
+// workspaceKey is the git config key recording the pairing set for a
+// workspace, the path being a subsection it can contain dots
+func workspaceKey(path string) string {
+	return fmt.Sprintf("duet-workspace.%s.pairing", path)
+}
+
+// SetWorkspacePairing records the initials of the pairing (author first) set
+// for a workspace, for telling which repositories drifted from it
+func (gc *GitConfig) SetWorkspacePairing(workspace *Workspace, initials ...string) (err error) {
+	return gc.setUnnamespacedKey(workspaceKey(workspace.Path), strings.Join(initials, " "))
+}
+
+// GetWorkspacePairing returns the initials of the pairing last set for a
+// workspace (nil if none)
+func (gc *GitConfig) GetWorkspacePairing(workspace *Workspace) (initials []string, err error) {
+	pairing, err := gc.getUnnamespacedKey(workspaceKey(workspace.Path))
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(pairing), nil
+}
+
+// SamePairing returns whether both pairings are the same people, in any order
+// (the author rotating through them doesn't make them differ)
+func SamePairing(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x, y := append([]string{}, a...), append([]string{}, b...)
+	sort.Strings(x)
+	sort.Strings(y)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}